@@ -0,0 +1,279 @@
+// Package codeformat mendefinisikan format kode redeem: panjang, alfabet,
+// prefix, pengelompokan dengan tanda hubung dan karakter pemeriksa
+// (Luhn mod N atau Damm) untuk mendeteksi salah ketik.
+package codeformat
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Jenis karakter pemeriksa
+const (
+	CheckNone = ""
+	CheckLuhn = "luhn"
+	CheckDamm = "damm"
+)
+
+const (
+	// CrockfordAlphabet adalah alfabet base32 Crockford tanpa karakter yang mirip (I, L, O, U)
+	CrockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+	// DecimalAlphabet adalah alfabet angka, wajib untuk CheckDamm
+	DecimalAlphabet = "0123456789"
+	// legacyAlphabet adalah alfabet kode 6 karakter sebelum format dapat diatur
+	legacyAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+	// MaxCodeLength adalah panjang maksimum kode yang tersimpan (lihat kolom redeem_codes.code)
+	MaxCodeLength = 32
+)
+
+var (
+	// ErrMalformed dikembalikan jika kode tidak sesuai panjang, prefix atau alfabet format
+	ErrMalformed = errors.New("code does not match the expected format")
+	// ErrChecksum dikembalikan jika karakter pemeriksa kode tidak cocok
+	ErrChecksum = errors.New("code check character does not match")
+)
+
+// Default adalah format untuk kampanye baru: 7 karakter Crockford base32
+// ditambah karakter pemeriksa Luhn, ditampilkan sebagai XXXX-XXXX
+var Default = Spec{
+	Length:    7,
+	Alphabet:  CrockfordAlphabet,
+	GroupSize: 4,
+	Check:     CheckLuhn,
+}
+
+// Legacy adalah format kode sebelum format dapat diatur, dipakai oleh
+// kampanye dan batch yang tidak menyimpan format
+var Legacy = Spec{
+	Length:   6,
+	Alphabet: legacyAlphabet,
+}
+
+// Spec adalah spesifikasi format kode
+type Spec struct {
+	// Length adalah jumlah karakter acak, tidak termasuk prefix dan karakter pemeriksa
+	Length    int    `json:"length"`
+	Alphabet  string `json:"alphabet"`
+	Prefix    string `json:"prefix"`
+	GroupSize int    `json:"group_size"` // 0 berarti tanpa tanda hubung
+	Check     string `json:"check"`
+}
+
+// IsZero mengembalikan true jika spec belum diatur
+func (s Spec) IsZero() bool {
+	return s.Length == 0
+}
+
+// OrDefault mengembalikan fallback jika spec belum diatur
+func (s Spec) OrDefault(fallback Spec) Spec {
+	if s.IsZero() {
+		return fallback
+	}
+	return s
+}
+
+// Validate memeriksa apakah spec bisa dipakai untuk membuat kode
+func (s Spec) Validate() error {
+	if s.Length < 4 {
+		return errors.New("code length must be at least 4")
+	}
+	if len(s.Alphabet) < 2 || len(s.Alphabet) > 256 {
+		return errors.New("code alphabet must have between 2 and 256 characters")
+	}
+	seen := make(map[rune]bool, len(s.Alphabet))
+	for _, r := range s.Alphabet {
+		if r > 127 || r == '-' || r == ' ' {
+			return fmt.Errorf("code alphabet contains invalid character %q", r)
+		}
+		if seen[r] {
+			return fmt.Errorf("code alphabet contains duplicate character %q", r)
+		}
+		seen[r] = true
+	}
+	for _, r := range s.Prefix {
+		if r > 127 || r == '-' || r == ' ' {
+			return fmt.Errorf("code prefix contains invalid character %q", r)
+		}
+	}
+	if s.GroupSize < 0 {
+		return errors.New("code group size must not be negative")
+	}
+	switch s.Check {
+	case CheckNone, CheckLuhn:
+	case CheckDamm:
+		if s.Alphabet != DecimalAlphabet {
+			return errors.New("damm check requires the decimal alphabet")
+		}
+	default:
+		return fmt.Errorf("unknown code check %q", s.Check)
+	}
+	if s.storedLength() > MaxCodeLength {
+		return fmt.Errorf("code must not be longer than %d characters", MaxCodeLength)
+	}
+	return nil
+}
+
+func (s Spec) storedLength() int {
+	n := len(s.Prefix) + s.Length
+	if s.Check != CheckNone {
+		n++
+	}
+	return n
+}
+
+// caseInsensitive mengembalikan true jika alfabet tidak memiliki huruf kecil,
+// sehingga input boleh diketik dengan huruf kecil
+func (s Spec) caseInsensitive() bool {
+	return strings.ToUpper(s.Alphabet) == s.Alphabet
+}
+
+// Generate membuat kode acak dalam bentuk kanonik (prefix, karakter acak dan
+// karakter pemeriksa tanpa tanda hubung). Karakter dipilih dengan rejection
+// sampling agar setiap karakter alfabet memiliki peluang yang sama.
+func (s Spec) Generate() (string, error) {
+	n := len(s.Alphabet)
+	limit := 256 - 256%n
+
+	body := make([]byte, 0, s.Length)
+	buf := make([]byte, s.Length*2)
+	for len(body) < s.Length {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		for _, b := range buf {
+			if int(b) >= limit {
+				continue
+			}
+			body = append(body, s.Alphabet[int(b)%n])
+			if len(body) == s.Length {
+				break
+			}
+		}
+	}
+
+	code := s.normalizedPrefix() + string(body)
+	switch s.Check {
+	case CheckLuhn:
+		code += string(s.Alphabet[luhnCheck(s.Alphabet, string(body))])
+	case CheckDamm:
+		code += string(DecimalAlphabet[dammCheck(string(body))])
+	}
+	return code, nil
+}
+
+// Format mengembalikan kode kanonik dengan tanda hubung untuk dicetak, misalnya
+// "PROMO-ABCD-EFGH". Prefix selalu dipisahkan dari bagian acak.
+func (s Spec) Format(code string) string {
+	prefix := s.normalizedPrefix()
+	if s.GroupSize == 0 || !strings.HasPrefix(code, prefix) {
+		return code
+	}
+
+	rest := code[len(prefix):]
+	var groups []string
+	if prefix != "" {
+		groups = append(groups, prefix)
+	}
+	for len(rest) > s.GroupSize {
+		groups = append(groups, rest[:s.GroupSize])
+		rest = rest[s.GroupSize:]
+	}
+	if rest != "" {
+		groups = append(groups, rest)
+	}
+	return strings.Join(groups, "-")
+}
+
+// Normalize mengubah input peserta ke bentuk kanonik: menghapus tanda hubung
+// dan spasi, menyeragamkan huruf besar jika alfabet tidak membedakannya, dan
+// untuk Crockford base32 mengganti O menjadi 0 serta I dan L menjadi 1.
+func (s Spec) Normalize(input string) string {
+	code := strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(input))
+	if s.caseInsensitive() {
+		code = strings.ToUpper(code)
+	}
+	if s.Alphabet == CrockfordAlphabet {
+		prefix := s.normalizedPrefix()
+		if strings.HasPrefix(code, prefix) {
+			code = prefix + strings.NewReplacer("O", "0", "I", "1", "L", "1").Replace(code[len(prefix):])
+		}
+	}
+	return code
+}
+
+func (s Spec) normalizedPrefix() string {
+	if s.caseInsensitive() {
+		return strings.ToUpper(s.Prefix)
+	}
+	return s.Prefix
+}
+
+// Verify memeriksa kode kanonik terhadap prefix, panjang, alfabet dan
+// karakter pemeriksa tanpa perlu mengakses database
+func (s Spec) Verify(code string) error {
+	prefix := s.normalizedPrefix()
+	if len(code) != s.storedLength() || !strings.HasPrefix(code, prefix) {
+		return ErrMalformed
+	}
+	rest := code[len(prefix):]
+	for i := 0; i < len(rest); i++ {
+		if strings.IndexByte(s.Alphabet, rest[i]) < 0 {
+			return ErrMalformed
+		}
+	}
+
+	body := rest[:s.Length]
+	switch s.Check {
+	case CheckLuhn:
+		if s.Alphabet[luhnCheck(s.Alphabet, body)] != rest[s.Length] {
+			return ErrChecksum
+		}
+	case CheckDamm:
+		if DecimalAlphabet[dammCheck(body)] != rest[s.Length] {
+			return ErrChecksum
+		}
+	}
+	return nil
+}
+
+// luhnCheck menghitung indeks karakter pemeriksa Luhn mod N untuk input
+func luhnCheck(alphabet, input string) int {
+	n := len(alphabet)
+	factor := 2
+	sum := 0
+	for i := len(input) - 1; i >= 0; i-- {
+		addend := factor * strings.IndexByte(alphabet, input[i])
+		if factor == 2 {
+			factor = 1
+		} else {
+			factor = 2
+		}
+		sum += addend/n + addend%n
+	}
+	return (n - sum%n) % n
+}
+
+var dammTable = [10][10]int{
+	{0, 3, 1, 7, 5, 9, 8, 6, 4, 2},
+	{7, 0, 9, 2, 1, 5, 4, 8, 6, 3},
+	{4, 2, 0, 6, 8, 7, 1, 3, 5, 9},
+	{1, 7, 5, 0, 9, 8, 3, 4, 2, 6},
+	{6, 1, 2, 3, 0, 4, 5, 9, 7, 8},
+	{3, 6, 7, 4, 2, 0, 9, 5, 8, 1},
+	{5, 8, 6, 9, 7, 2, 0, 1, 3, 4},
+	{8, 9, 4, 5, 3, 6, 2, 0, 1, 7},
+	{9, 4, 3, 8, 6, 1, 7, 2, 0, 5},
+	{2, 5, 8, 1, 4, 3, 6, 7, 9, 0},
+}
+
+// dammCheck menghitung digit pemeriksa Damm untuk input angka
+func dammCheck(input string) int {
+	interim := 0
+	for i := 0; i < len(input); i++ {
+		interim = dammTable[interim][input[i]-'0']
+	}
+	return interim
+}
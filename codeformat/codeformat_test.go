@@ -0,0 +1,130 @@
+package codeformat
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLuhnCheck(t *testing.T) {
+	tests := []struct {
+		alphabet string
+		input    string
+		want     byte
+	}{
+		// Contoh Luhn mod 10 dan Luhn mod N dari Wikipedia
+		{DecimalAlphabet, "7992739871", '3'},
+		{"abcdef", "abcdef", 'e'},
+		{DecimalAlphabet, "0", '0'},
+	}
+	for _, tt := range tests {
+		if got := tt.alphabet[luhnCheck(tt.alphabet, tt.input)]; got != tt.want {
+			t.Errorf("luhnCheck(%q, %q) = %q, want %q", tt.alphabet, tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestDammCheck(t *testing.T) {
+	tests := []struct {
+		input string
+		want  int
+	}{
+		// Contoh dari Wikipedia
+		{"572", 4},
+		{"5724", 0},
+		{"", 0},
+	}
+	for _, tt := range tests {
+		if got := dammCheck(tt.input); got != tt.want {
+			t.Errorf("dammCheck(%q) = %d, want %d", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeVerifyRoundTrip(t *testing.T) {
+	spec := Spec{Length: 7, Alphabet: CrockfordAlphabet, Prefix: "promo", GroupSize: 4, Check: CheckLuhn}
+	if err := spec.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	code := "PROMO0A1B2C3" + string(CrockfordAlphabet[luhnCheck(CrockfordAlphabet, "0A1B2C3")])
+
+	printed := spec.Format(code)
+	if want := "PROMO-0A1B-2C3" + code[len(code)-1:]; printed != want {
+		t.Fatalf("Format(%q) = %q, want %q", code, printed, want)
+	}
+
+	// Peserta mengetik huruf kecil, spasi, dan O/I/L sebagai ganti 0 dan 1
+	typed := " promo " + strings.ToLower(strings.NewReplacer("0", "O", "1", "l").Replace(printed[len("PROMO-"):])) + " "
+	if got := spec.Normalize(typed); got != code {
+		t.Fatalf("Normalize(%q) = %q, want %q", typed, got, code)
+	}
+	if err := spec.Verify(spec.Normalize(typed)); err != nil {
+		t.Errorf("Verify(Normalize(%q)) error = %v", typed, err)
+	}
+	if got := spec.Normalize(strings.Replace(printed, "1", "I", 1)); got != code {
+		t.Errorf("Normalize(%q) = %q, want %q", strings.Replace(printed, "1", "I", 1), got, code)
+	}
+
+	for i := 0; i < 10; i++ {
+		generated, err := spec.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := spec.Verify(spec.Normalize(spec.Format(generated))); err != nil {
+			t.Errorf("Verify(%q) error = %v", generated, err)
+		}
+	}
+}
+
+func TestLegacyNormalizeKeepsCase(t *testing.T) {
+	if got := Legacy.Normalize(" aB3-xYz "); got != "aB3xYz" {
+		t.Errorf("Legacy.Normalize() = %q, want %q", got, "aB3xYz")
+	}
+	if err := Legacy.Verify("aB3xYz"); err != nil {
+		t.Errorf("Legacy.Verify() error = %v", err)
+	}
+}
+
+func TestVerifyRejectsSubstitution(t *testing.T) {
+	specs := []Spec{
+		Default,
+		{Length: 8, Alphabet: DecimalAlphabet, Prefix: "77", Check: CheckDamm},
+		{Length: 6, Alphabet: DecimalAlphabet, Check: CheckLuhn},
+	}
+	for _, spec := range specs {
+		code, err := spec.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := spec.Verify(code); err != nil {
+			t.Fatalf("Verify(%q) error = %v", code, err)
+		}
+
+		prefix := spec.normalizedPrefix()
+		for i := len(prefix); i < len(code); i++ {
+			for j := 0; j < len(spec.Alphabet); j++ {
+				if spec.Alphabet[j] == code[i] {
+					continue
+				}
+				substituted := code[:i] + string(spec.Alphabet[j]) + code[i+1:]
+				if err := spec.Verify(substituted); err != ErrChecksum {
+					t.Errorf("%s: Verify(%q) error = %v, want %v", spec.Check, substituted, err, ErrChecksum)
+				}
+			}
+		}
+	}
+}
+
+func TestVerifyMalformed(t *testing.T) {
+	code, err := Default.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, input := range []string{code[1:], code + "A", "U" + code[1:], strings.ToLower(code)} {
+		if input == code {
+			continue
+		}
+		if err := Default.Verify(input); err != ErrMalformed {
+			t.Errorf("Verify(%q) error = %v, want %v", input, err, ErrMalformed)
+		}
+	}
+}
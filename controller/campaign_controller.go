@@ -0,0 +1,176 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gamaput/go-redeem/codeformat"
+	"github.com/gamaput/go-redeem/model"
+	"github.com/gamaput/go-redeem/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// CampaignController : represent the campaign's controller contract
+type CampaignController interface {
+	CreateCampaign(*gin.Context)
+	GetCampaignByID(*gin.Context)
+	UpdateCampaign(*gin.Context)
+	DeleteCampaign(*gin.Context)
+	GetAllCampaigns(*gin.Context)
+}
+
+type campaignController struct {
+	campaignRepo repository.CampaignRepository
+}
+
+// NewCampaignController -> returns new campaign controller
+func NewCampaignController(campaignRepo repository.CampaignRepository) CampaignController {
+	return campaignController{
+		campaignRepo: campaignRepo,
+	}
+}
+
+func (cc campaignController) CreateCampaign(c *gin.Context) {
+	var input model.Campaign
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Kampanye baru selalu dimulai sebagai draft jika status tidak diisi
+	if input.Status == "" {
+		input.Status = model.CampaignStatusDraft
+	}
+	input.CodeFormat = input.CodeFormat.OrDefault(codeformat.Default)
+
+	if err := validateCampaignInput(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	campaign := model.Campaign{
+		Name:          input.Name,
+		StartAt:       input.StartAt,
+		EndAt:         input.EndAt,
+		Status:        input.Status,
+		NoPrizeWeight: input.NoPrizeWeight,
+		CodeFormat:    input.CodeFormat,
+	}
+
+	campaign, err := cc.campaignRepo.CreateCampaign(campaign)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, campaign)
+}
+
+func validateCampaignInput(input model.Campaign) error {
+	if input.Name == "" {
+		return errors.New("name is required")
+	}
+	if input.StartAt.IsZero() || input.EndAt.IsZero() {
+		return errors.New("start_at and end_at are required")
+	}
+	if !input.EndAt.After(input.StartAt) {
+		return errors.New("end_at must be after start_at")
+	}
+	if !model.IsValidCampaignStatus(input.Status) {
+		return errors.New("status must be one of draft, active, paused or ended")
+	}
+	if err := input.CodeFormat.Validate(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (cc campaignController) GetCampaignByID(c *gin.Context) {
+	id := c.Param("campaign")
+	intID, err := strconv.Atoi(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid campaign ID"})
+		return
+	}
+
+	campaign, err := cc.campaignRepo.GetCampaignByID(uint(intID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, campaign)
+}
+
+func (cc campaignController) UpdateCampaign(c *gin.Context) {
+	var input model.Campaign
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	id := c.Param("campaign")
+	intID, err := strconv.Atoi(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid campaign ID"})
+		return
+	}
+
+	campaign, err := cc.campaignRepo.GetCampaignByID(uint(intID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+		return
+	}
+
+	// Format kode hanya diubah jika diisi
+	input.CodeFormat = input.CodeFormat.OrDefault(campaign.CodeSpec())
+	if err := validateCampaignInput(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	campaign.Name = input.Name
+	campaign.StartAt = input.StartAt
+	campaign.EndAt = input.EndAt
+	campaign.Status = input.Status
+	campaign.NoPrizeWeight = input.NoPrizeWeight
+	campaign.CodeFormat = input.CodeFormat
+
+	campaign, err = cc.campaignRepo.UpdateCampaign(campaign)
+	if errors.Is(err, repository.ErrCodeFormatLocked) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update campaign"})
+		return
+	}
+
+	c.JSON(http.StatusOK, campaign)
+}
+
+func (cc campaignController) DeleteCampaign(c *gin.Context) {
+	var campaign model.Campaign
+	id := c.Param("campaign")
+	intID, _ := strconv.Atoi(id)
+	campaign.ID = uint(intID)
+	campaign, err := cc.campaignRepo.DeleteCampaign(campaign)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid campaign ID"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Campaign deleted successfully"})
+}
+
+func (cc campaignController) GetAllCampaigns(c *gin.Context) {
+
+	campaigns, err := cc.campaignRepo.GetAllCampaigns()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, campaigns)
+}
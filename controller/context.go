@@ -0,0 +1,33 @@
+package controller
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// currentUserID mengembalikan ID user yang sedang login dari context yang
+// diisi oleh middleware.AuthorizeJWT, atau 0 jika tidak ada
+func currentUserID(ctx *gin.Context) uint {
+	sub, exists := ctx.Get("userID")
+	if !exists {
+		return 0
+	}
+	id, err := strconv.ParseUint(fmt.Sprint(sub), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return uint(id)
+}
+
+// currentSessionID mengembalikan ID sesi dari access token yang dipakai, atau 0 jika tidak ada
+func currentSessionID(ctx *gin.Context) uint {
+	return ctx.GetUint("sessionID")
+}
+
+// markInvalidAttempt menandai request gagal karena input tebakan yang salah,
+// agar dihitung untuk lockout bertahap oleh middleware.RateLimiter
+func markInvalidAttempt(ctx *gin.Context) {
+	ctx.Set("invalidAttempt", true)
+}
@@ -0,0 +1,90 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gamaput/go-redeem/repository"
+	"github.com/gamaput/go-redeem/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// lockoutEventLimit adalah jumlah maksimum event lockout yang dikembalikan
+const lockoutEventLimit = 100
+
+// dummyPasswordHash dibandingkan untuk email yang tidak terdaftar, agar waktu
+// respons sama dengan password yang salah dan tidak membedakan email terdaftar
+var dummyPasswordHash = func() string {
+	password := "not-a-real-password"
+	utils.HashPassword(&password)
+	return password
+}()
+
+// respondInvalidCredentials menolak sign in dengan respons 401 yang sama untuk
+// email tidak terdaftar, password salah, maupun akun atau IP yang terkunci,
+// sehingga respons tidak membocorkan akun mana yang ada atau sedang dikunci
+func respondInvalidCredentials(ctx *gin.Context) {
+	ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+}
+
+// GetLoginLockouts mengembalikan akun dan IP yang sedang terkunci, beserta
+// riwayat lockout dan pembukaannya yang terbaru
+func (h userController) GetLoginLockouts(ctx *gin.Context) {
+	lockouts, err := h.loginRepo.GetActiveLockouts(time.Now())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	events, err := h.loginRepo.GetLockoutEvents(0, lockoutEventLimit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"lockouts": lockouts, "events": events})
+}
+
+// GetUserLockoutEvents mengembalikan riwayat lockout dan pembukaan akun satu user
+func (h userController) GetUserLockoutEvents(ctx *gin.Context) {
+	userID, err := strconv.Atoi(ctx.Param("user"))
+	if err != nil || userID <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+	events, err := h.loginRepo.GetLockoutEvents(uint(userID), lockoutEventLimit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, events)
+}
+
+// UnlockLogin membuka lockout akun atau IP sebelum waktunya; admin yang
+// membukanya dicatat di riwayat lockout
+func (h userController) UnlockLogin(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("lockout"))
+	if err != nil || id <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid lockout id"})
+		return
+	}
+	var request struct {
+		Reason string `json:"reason"`
+	}
+	ctx.ShouldBindJSON(&request)
+	if request.Reason == "" {
+		request.Reason = "unlocked by admin"
+	}
+
+	throttle, err := h.loginRepo.Unlock(uint(id), currentUserID(ctx), request.Reason)
+	if err != nil {
+		if errors.Is(err, repository.ErrLoginThrottleNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, throttle)
+}
@@ -0,0 +1,271 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gamaput/go-redeem/repository"
+	"github.com/gamaput/go-redeem/totp"
+	"github.com/gamaput/go-redeem/utils"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// totpIssuer ditampilkan di aplikasi authenticator
+	totpIssuer = "Go Redeem"
+	// recoveryCodeCount adalah jumlah recovery code yang dibuat sekaligus
+	recoveryCodeCount = 10
+)
+
+// mfaRequired mengembalikan true jika policy Casbin (sub, "mfa", "required")
+// berlaku untuk user, misalnya "p, admin, mfa, required" untuk semua admin
+func mfaRequired(enforcer *casbin.Enforcer, userID uint) (bool, error) {
+	if err := enforcer.LoadPolicy(); err != nil {
+		return false, err
+	}
+	return enforcer.Enforce(fmt.Sprint(userID), "mfa", "required")
+}
+
+// mfaRequest adalah body untuk memeriksa faktor kedua: kode TOTP atau recovery code
+type mfaRequest struct {
+	MFAToken     string `json:"mfa_token"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// verifySecondFactor memeriksa kode TOTP atau recovery code pada request
+func (h userController) verifySecondFactor(userID uint, request mfaRequest) error {
+	switch {
+	case request.Code != "":
+		return h.mfaRepo.VerifyTOTP(userID, request.Code)
+	case request.RecoveryCode != "":
+		return h.mfaRepo.UseRecoveryCode(userID, request.RecoveryCode)
+	}
+	return repository.ErrTOTPCodeInvalid
+}
+
+// respondMFAError menulis respons untuk error dari verifikasi faktor kedua.
+// Kode yang salah ditandai sebagai percobaan tidak valid untuk lockout.
+func respondMFAError(ctx *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, repository.ErrTOTPCodeInvalid), errors.Is(err, repository.ErrRecoveryCodeInvalid):
+		markInvalidAttempt(ctx)
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, repository.ErrTOTPNotEnabled), errors.Is(err, repository.ErrTOTPNotEnrolled), errors.Is(err, repository.ErrTOTPAlreadyEnabled):
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+// VerifyMFA menukar challenge token dari SignInUser dan kode TOTP (atau
+// recovery code) dengan access token. Jika user sedang mendaftarkan TOTP
+// karena diwajibkan policy, kode pertama yang benar sekaligus mengaktifkan 2FA
+// dan recovery code dikembalikan.
+func (h userController) VerifyMFA(ctx *gin.Context) {
+	var request mfaRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil || request.MFAToken == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "mfa_token and code or recovery_code are required"})
+		return
+	}
+	challenge, err := utils.ValidateMFAToken(request.MFAToken)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired mfa_token"})
+		return
+	}
+	userID := challenge.UserID
+	user, err := h.userRepo.GetUser(int(userID))
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired mfa_token"})
+		return
+	}
+
+	userTOTP, err := h.mfaRepo.GetTOTP(userID)
+	if err != nil {
+		respondMFAError(ctx, err, "Failed to verify two-factor code")
+		return
+	}
+	if userTOTP.ConfirmedAt == nil {
+		recoveryCodes, err := totp.NewRecoveryCodes(recoveryCodeCount)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify two-factor code"})
+			return
+		}
+		if err := h.mfaRepo.ConfirmTOTP(userID, request.Code, recoveryCodes); err != nil {
+			respondMFAError(ctx, err, "Failed to verify two-factor code")
+			return
+		}
+		if !h.useMFAChallenge(ctx, challenge) {
+			return
+		}
+		h.startSession(ctx, user, gin.H{"recovery_codes": recoveryCodes})
+		return
+	}
+
+	if err := h.verifySecondFactor(userID, request); err != nil {
+		respondMFAError(ctx, err, "Failed to verify two-factor code")
+		return
+	}
+	if !h.useMFAChallenge(ctx, challenge) {
+		return
+	}
+	h.startSession(ctx, user, nil)
+}
+
+// useMFAChallenge mencatat challenge token yang berhasil ditukar agar tidak
+// bisa dipakai lagi, dan menulis respons error jika token sudah pernah dipakai
+func (h userController) useMFAChallenge(ctx *gin.Context, challenge utils.MFAChallenge) bool {
+	err := h.mfaRepo.UseMFAChallenge(challenge)
+	switch {
+	case errors.Is(err, repository.ErrMFAChallengeUsed):
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired mfa_token"})
+		return false
+	case err != nil:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify two-factor code"})
+		return false
+	}
+	return true
+}
+
+// BeginMFAEnrollment memulai pendaftaran TOTP dengan challenge token, untuk
+// user yang diwajibkan 2FA oleh policy tetapi belum mendaftar
+func (h userController) BeginMFAEnrollment(ctx *gin.Context) {
+	var request mfaRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil || request.MFAToken == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "mfa_token is required"})
+		return
+	}
+	challenge, err := utils.ValidateMFAToken(request.MFAToken)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired mfa_token"})
+		return
+	}
+	h.beginEnrollment(ctx, challenge.UserID)
+}
+
+// GetMFAStatus mengembalikan status 2FA user yang sedang login
+func (h userController) GetMFAStatus(ctx *gin.Context) {
+	userID := currentUserID(ctx)
+	userTOTP, err := h.mfaRepo.GetTOTP(userID)
+	if errors.Is(err, repository.ErrTOTPNotEnrolled) {
+		ctx.JSON(http.StatusOK, gin.H{"enabled": false, "pending": false, "recovery_codes_remaining": 0})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	remaining, err := h.mfaRepo.CountRecoveryCodes(userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"enabled": userTOTP.ConfirmedAt != nil, "pending": userTOTP.ConfirmedAt == nil,
+		"confirmed_at": userTOTP.ConfirmedAt, "recovery_codes_remaining": remaining})
+}
+
+// EnrollTOTP memulai pendaftaran TOTP untuk user yang sedang login. 2FA baru
+// aktif setelah dikonfirmasi di ConfirmTOTP.
+func (h userController) EnrollTOTP(ctx *gin.Context) {
+	h.beginEnrollment(ctx, currentUserID(ctx))
+}
+
+// beginEnrollment membuat secret TOTP baru dan mengembalikan provisioning URI
+// yang ditampilkan sebagai QR code
+func (h userController) beginEnrollment(ctx *gin.Context, userID uint) {
+	user, err := h.userRepo.GetUser(int(userID))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start enrollment"})
+		return
+	}
+	if err := h.mfaRepo.BeginTOTPEnrollment(userID, secret); err != nil {
+		respondMFAError(ctx, err, "Failed to start enrollment")
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"secret": secret, "provisioning_uri": totp.ProvisioningURI(totpIssuer, user.Email, secret)})
+}
+
+// ConfirmTOTP mengaktifkan 2FA dengan kode pertama dari aplikasi authenticator
+// dan mengembalikan recovery code. Recovery code hanya ditampilkan sekali.
+func (h userController) ConfirmTOTP(ctx *gin.Context) {
+	var request mfaRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil || request.Code == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "code is required"})
+		return
+	}
+	recoveryCodes, err := totp.NewRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to confirm two-factor authentication"})
+		return
+	}
+	if err := h.mfaRepo.ConfirmTOTP(currentUserID(ctx), request.Code, recoveryCodes); err != nil {
+		respondMFAError(ctx, err, "Failed to confirm two-factor authentication")
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"msg": "Two-factor authentication enabled", "recovery_codes": recoveryCodes})
+}
+
+// RegenerateRecoveryCodes mengganti semua recovery code user yang sedang login
+// setelah memeriksa kode TOTP
+func (h userController) RegenerateRecoveryCodes(ctx *gin.Context) {
+	var request mfaRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil || request.Code == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "code is required"})
+		return
+	}
+	userID := currentUserID(ctx)
+	if err := h.mfaRepo.VerifyTOTP(userID, request.Code); err != nil {
+		respondMFAError(ctx, err, "Failed to regenerate recovery codes")
+		return
+	}
+	recoveryCodes, err := totp.NewRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to regenerate recovery codes"})
+		return
+	}
+	if err := h.mfaRepo.ReplaceRecoveryCodes(userID, recoveryCodes); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to regenerate recovery codes"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"recovery_codes": recoveryCodes})
+}
+
+// DisableTOTP menonaktifkan 2FA user yang sedang login setelah memeriksa kode
+// TOTP atau recovery code. User yang diwajibkan 2FA oleh policy tidak bisa
+// menonaktifkannya.
+func (h userController) DisableTOTP(enforcer *casbin.Enforcer) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var request mfaRequest
+		if err := ctx.ShouldBindJSON(&request); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "code or recovery_code is required"})
+			return
+		}
+		userID := currentUserID(ctx)
+		required, err := mfaRequired(enforcer, userID)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication"})
+			return
+		}
+		if required {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "Two-factor authentication is required for your role"})
+			return
+		}
+		if err := h.verifySecondFactor(userID, request); err != nil {
+			respondMFAError(ctx, err, "Failed to disable two-factor authentication")
+			return
+		}
+		if err := h.mfaRepo.DisableTOTP(userID); err != nil {
+			respondMFAError(ctx, err, "Failed to disable two-factor authentication")
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"msg": "Two-factor authentication disabled"})
+	}
+}
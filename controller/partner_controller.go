@@ -0,0 +1,250 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gamaput/go-redeem/middleware"
+	"github.com/gamaput/go-redeem/model"
+	"github.com/gamaput/go-redeem/repository"
+	"github.com/gamaput/go-redeem/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// PartnerController : represent the partner's controller contract
+type PartnerController interface {
+	GetAllPartners(*gin.Context)
+	CreatePartner(*gin.Context)
+	GetPartnerByID(*gin.Context)
+	UpdatePartner(*gin.Context)
+	RotatePartnerCredentials(*gin.Context)
+	IssueCode(*gin.Context)
+}
+
+type partnerController struct {
+	partnerRepo  repository.PartnerRepository
+	campaignRepo repository.CampaignRepository
+}
+
+// NewPartnerController -> returns new partner controller
+func NewPartnerController(partnerRepo repository.PartnerRepository, campaignRepo repository.CampaignRepository) PartnerController {
+	return partnerController{
+		partnerRepo:  partnerRepo,
+		campaignRepo: campaignRepo,
+	}
+}
+
+type partnerRequest struct {
+	Name             string `json:"name"`
+	CampaignID       uint   `json:"campaign_id"`
+	Active           *bool  `json:"active"`
+	DailyQuota       int    `json:"daily_quota"`
+	TotalQuota       int    `json:"total_quota"`
+	MaxUses          int    `json:"max_uses"`
+	PerIdentityLimit int    `json:"per_identity_limit"`
+}
+
+func (pc partnerController) validatePartnerRequest(request *partnerRequest) error {
+	if request.Name == "" {
+		return errors.New("name is required")
+	}
+	if _, err := pc.campaignRepo.GetCampaignByID(request.CampaignID); err != nil {
+		return errors.New("campaign not found")
+	}
+	if request.DailyQuota < 0 || request.TotalQuota < 0 {
+		return errors.New("quotas must not be negative")
+	}
+	if request.MaxUses == 0 {
+		request.MaxUses = 1
+	}
+	if request.PerIdentityLimit == 0 {
+		request.PerIdentityLimit = 1
+	}
+	return validateUsageLimits(request.MaxUses, request.PerIdentityLimit)
+}
+
+// newPartnerCredentials membuat API key dan secret baru untuk partner
+func newPartnerCredentials() (apiKey, secret string, err error) {
+	if apiKey, err = utils.RandomToken(24); err != nil {
+		return "", "", err
+	}
+	apiKey = "pk_" + apiKey
+	if secret, err = utils.RandomToken(32); err != nil {
+		return "", "", err
+	}
+	return apiKey, secret, nil
+}
+
+func (pc partnerController) GetAllPartners(c *gin.Context) {
+	partners, err := pc.partnerRepo.GetAllPartners()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, partners)
+}
+
+// CreatePartner mendaftarkan partner baru. API key dan secret hanya
+// ditampilkan pada respons ini dan harus disimpan oleh partner.
+func (pc partnerController) CreatePartner(c *gin.Context) {
+	var request partnerRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+	if err := pc.validatePartnerRequest(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	apiKey, secret, err := newPartnerCredentials()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate partner credentials"})
+		return
+	}
+
+	partner := model.Partner{
+		Name:             request.Name,
+		CampaignID:       request.CampaignID,
+		Secret:           secret,
+		Active:           request.Active == nil || *request.Active,
+		DailyQuota:       request.DailyQuota,
+		TotalQuota:       request.TotalQuota,
+		MaxUses:          request.MaxUses,
+		PerIdentityLimit: request.PerIdentityLimit,
+		CreatedBy:        currentUserID(c),
+	}
+	if err := pc.partnerRepo.CreatePartner(&partner, apiKey); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create partner"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"partner": partner, "api_key": apiKey, "secret": secret})
+}
+
+func (pc partnerController) GetPartnerByID(c *gin.Context) {
+	partner, ok := pc.partnerFromParam(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, partner)
+}
+
+func (pc partnerController) UpdatePartner(c *gin.Context) {
+	partner, ok := pc.partnerFromParam(c)
+	if !ok {
+		return
+	}
+
+	var request partnerRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+	if err := pc.validatePartnerRequest(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	partner.Name = request.Name
+	partner.CampaignID = request.CampaignID
+	partner.DailyQuota = request.DailyQuota
+	partner.TotalQuota = request.TotalQuota
+	partner.MaxUses = request.MaxUses
+	partner.PerIdentityLimit = request.PerIdentityLimit
+	if request.Active != nil {
+		partner.Active = *request.Active
+	}
+	if err := pc.partnerRepo.UpdatePartner(partner); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update partner"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Partner updated successfully", "partner": partner})
+}
+
+// RotatePartnerCredentials mengganti API key dan secret partner
+func (pc partnerController) RotatePartnerCredentials(c *gin.Context) {
+	partner, ok := pc.partnerFromParam(c)
+	if !ok {
+		return
+	}
+
+	apiKey, secret, err := newPartnerCredentials()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate partner credentials"})
+		return
+	}
+	if err := pc.partnerRepo.RotatePartnerCredentials(partner.ID, apiKey, secret); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rotate partner credentials"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Partner credentials rotated successfully", "api_key": apiKey, "secret": secret})
+}
+
+// IssueCode menerbitkan kode redeem untuk nomor struk dari POS partner.
+// Request dengan nomor struk yang sama mendapatkan kode yang sama.
+func (pc partnerController) IssueCode(c *gin.Context) {
+	partner := c.MustGet(middleware.PartnerKey).(model.Partner)
+
+	var request struct {
+		ReceiptNumber string `json:"receipt_number"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+	request.ReceiptNumber = strings.TrimSpace(request.ReceiptNumber)
+	if request.ReceiptNumber == "" || len(request.ReceiptNumber) > 64 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "receipt_number is required and must be at most 64 characters"})
+		return
+	}
+
+	issuance, created, err := pc.partnerRepo.IssuePartnerCode(partner, request.ReceiptNumber)
+	switch {
+	case errors.Is(err, repository.ErrPartnerDailyQuotaExceeded),
+		errors.Is(err, repository.ErrPartnerTotalQuotaExceeded):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, repository.ErrCampaignNotActive):
+		c.JSON(http.StatusConflict, gin.H{"error": "Campaign is not active"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue code"})
+		return
+	}
+
+	campaign, err := pc.campaignRepo.GetCampaignByID(partner.CampaignID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue code"})
+		return
+	}
+
+	status := http.StatusCreated
+	if !created {
+		status = http.StatusOK
+	}
+	c.JSON(status, gin.H{
+		"code":           campaign.CodeSpec().Format(issuance.Code),
+		"receipt_number": issuance.ReceiptNumber,
+		"issued_at":      issuance.CreatedAt,
+		"valid_until":    campaign.EndAt,
+	})
+}
+
+func (pc partnerController) partnerFromParam(c *gin.Context) (model.Partner, bool) {
+	id, err := strconv.Atoi(c.Param("partner"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid partner ID"})
+		return model.Partner{}, false
+	}
+	partner, err := pc.partnerRepo.GetPartnerByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Partner not found"})
+		return model.Partner{}, false
+	}
+	return partner, true
+}
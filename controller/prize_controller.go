@@ -22,15 +22,19 @@ type PrizeController interface {
 	DeletePrize(c *gin.Context)
 	UpdatePrize(c *gin.Context)
 	GetPrizeByID(c *gin.Context)
+	RecordStockMovement(c *gin.Context)
+	GetStockMovements(c *gin.Context)
 }
 
 type prizeController struct {
-	Repo repository.PrizeRepository
+	Repo         repository.PrizeRepository
+	CampaignRepo repository.CampaignRepository
 }
 
-func NewPrizeController(repo repository.PrizeRepository) PrizeController {
+func NewPrizeController(repo repository.PrizeRepository, campaignRepo repository.CampaignRepository) PrizeController {
 	return prizeController{
-		Repo: repo,
+		Repo:         repo,
+		CampaignRepo: campaignRepo,
 	}
 }
 func (pc prizeController) GenerateRandomPrize(c *gin.Context) {
@@ -63,9 +67,21 @@ func (pc prizeController) GenerateRandomPrize(c *gin.Context) {
 	c.JSON(http.StatusOK, randomPrizes)
 }
 
-// GetRandomPrize mengambil hadiah secara acak dan mengembalikannya dalam respons JSON
+// GetRandomPrize mengambil hadiah kampanye secara acak dan mengembalikannya dalam respons JSON
 func (pc prizeController) GetRandomPrize(c *gin.Context) {
-	prize, err := pc.Repo.GetRandomPrize()
+	campaignID, err := strconv.Atoi(c.Query("campaign_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid campaign ID"})
+		return
+	}
+
+	campaign, err := pc.CampaignRepo.GetCampaignByID(uint(campaignID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+		return
+	}
+
+	prize, err := pc.Repo.GetRandomPrize(campaign)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -86,12 +102,21 @@ func (pc prizeController) CreatePrize(c *gin.Context) {
 		return
 	}
 
+	if _, err := pc.CampaignRepo.GetCampaignByID(input.CampaignID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Campaign not found"})
+		return
+	}
+
 	prize := model.Prize{
-		Name:     input.Name,
-		Quantity: input.Quantity,
+		CampaignID: input.CampaignID,
+		Name:       input.Name,
+		Quantity:   input.Quantity,
+		Weight:     input.Weight,
+
+		LowStockThreshold: input.LowStockThreshold,
 	}
 
-	if err := pc.Repo.CreatePrize(&prize); err != nil {
+	if err := pc.Repo.CreatePrize(&prize, currentUserID(c)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -100,12 +125,23 @@ func (pc prizeController) CreatePrize(c *gin.Context) {
 }
 
 func validateCreatePrizeInput(input model.Prize) error {
+	if input.CampaignID == 0 {
+		return errors.New("campaign_id is required")
+	}
 	if input.Name == "" {
 		return errors.New("name is required")
 	}
 	if input.Quantity <= 0 {
 		return errors.New("quantity must be greater than 0")
 	}
+	// Bobot 0 tidak bisa dibedakan dari field yang kosong, sehingga akan
+	// tersimpan sebagai bobot bawaan 1
+	if input.Weight == 0 {
+		return errors.New("weight must be greater than 0")
+	}
+	if input.LowStockThreshold < 0 {
+		return errors.New("low_stock_threshold must not be negative")
+	}
 	return nil
 }
 
@@ -120,12 +156,21 @@ func (pr prizeController) GetAllPrizes(c *gin.Context) {
 	c.JSON(http.StatusOK, products)
 }
 
+// updatePrizeRequest memakai pointer agar field yang tidak dikirim tidak
+// mengubah nilai yang tersimpan
+type updatePrizeRequest struct {
+	Name              *string `json:"name"`
+	Quantity          *int    `json:"quantity"`
+	Weight            *uint   `json:"weight"`
+	LowStockThreshold *int    `json:"low_stock_threshold"`
+}
+
 // UpdatePrize updates a prize
 func (c prizeController) UpdatePrize(ctx *gin.Context) {
-	var prize model.Prize
+	var request updatePrizeRequest
 	id := ctx.Param("prize")
 
-	if err := ctx.ShouldBindJSON(&prize); err != nil {
+	if err := ctx.ShouldBindJSON(&request); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
 		return
 	}
@@ -142,15 +187,33 @@ func (c prizeController) UpdatePrize(ctx *gin.Context) {
 		return
 	}
 
-	// Check if the requested quantity is negative
-	if prize.Quantity < 0 {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid quantity"})
+	// Stok hanya bisa diubah lewat buku stok agar setiap perubahan tercatat
+	if request.Quantity != nil && *request.Quantity != existingPrize.Quantity {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "quantity cannot be updated directly, record a stock movement instead"})
 		return
 	}
 
-	// Update the existing prize object
-	existingPrize.Name = prize.Name
-	existingPrize.Quantity = prize.Quantity
+	if request.Name != nil {
+		if *request.Name == "" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+			return
+		}
+		existingPrize.Name = *request.Name
+	}
+	if request.Weight != nil {
+		if *request.Weight == 0 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "weight must be greater than 0"})
+			return
+		}
+		existingPrize.Weight = *request.Weight
+	}
+	if request.LowStockThreshold != nil {
+		if *request.LowStockThreshold < 0 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "low_stock_threshold must not be negative"})
+			return
+		}
+		existingPrize.LowStockThreshold = *request.LowStockThreshold
+	}
 
 	if err := c.Repo.UpdatePrize(&existingPrize); err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update prize"})
@@ -192,3 +255,78 @@ func (pc prizeController) GetPrizeByID(c *gin.Context) {
 
 	c.JSON(http.StatusOK, prize)
 }
+
+// RecordStockMovement mencatat pergerakan stok hadiah (restock, adjustment,
+// return atau expiry). Quantity bernilai negatif untuk pengurangan stok.
+func (pc prizeController) RecordStockMovement(c *gin.Context) {
+	intID, err := strconv.Atoi(c.Param("prize"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid prize ID"})
+		return
+	}
+
+	var request struct {
+		Type     string `json:"type"`
+		Quantity int    `json:"quantity"`
+		Reason   string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+	// Entri award hanya dicatat oleh sistem saat penukaran
+	if request.Type == model.InventoryAward {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "award entries are recorded by redemptions"})
+		return
+	}
+	if err := model.ValidateInventoryDelta(request.Type, request.Quantity); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if request.Reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reason is required"})
+		return
+	}
+
+	entry := model.InventoryEntry{
+		PrizeID: uint(intID),
+		Type:    request.Type,
+		Delta:   request.Quantity,
+		Reason:  request.Reason,
+		ActorID: currentUserID(c),
+	}
+	switch err := pc.Repo.RecordInventory(&entry); {
+	case errors.Is(err, repository.ErrPrizeNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Prize not found"})
+		return
+	case errors.Is(err, repository.ErrInsufficientStock):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record stock movement"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, entry)
+}
+
+// GetStockMovements mengembalikan riwayat pergerakan stok hadiah
+func (pc prizeController) GetStockMovements(c *gin.Context) {
+	intID, err := strconv.Atoi(c.Param("prize"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid prize ID"})
+		return
+	}
+	if _, err := pc.Repo.GetPrizeByID(uint(intID)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Prize not found"})
+		return
+	}
+
+	entries, err := pc.Repo.GetInventoryEntries(uint(intID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
@@ -1,123 +1,534 @@
 package controller
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/gamaput/go-redeem/codeformat"
 	"github.com/gamaput/go-redeem/model"
+	"github.com/gamaput/go-redeem/nik"
+	"github.com/gamaput/go-redeem/phone"
 	"github.com/gamaput/go-redeem/repository"
 	"github.com/gamaput/go-redeem/utils"
 	"github.com/gin-gonic/gin"
 )
 
+// maxBatchCodeCount membatasi jumlah kode dalam satu batch
+const maxBatchCodeCount = 100000
+
+// Kode error yang dapat dibaca mesin pada respons /api/redeem, dikirim
+// bersama pesan error pada field "error_code"
+const (
+	RedeemErrInvalidRequest    = "invalid_request"
+	RedeemErrMissingFields     = "missing_fields"
+	RedeemErrInvalidNIK        = "invalid_nik"
+	RedeemErrInvalidPhone      = "invalid_phone"
+	RedeemErrMalformedCode     = "code_malformed"
+	RedeemErrCodeNotFound      = "code_not_found"
+	RedeemErrAlreadyRedeemed   = "code_already_redeemed"
+	RedeemErrCodeNotYetValid   = "code_not_yet_valid"
+	RedeemErrCodeExpired       = "code_expired"
+	RedeemErrCodeRevoked       = "code_revoked"
+	RedeemErrLimitReached      = "participant_limit_reached"
+	RedeemErrCampaignNotActive = "campaign_not_active"
+	RedeemErrInternal          = "internal_error"
+)
+
 type RedeemCodeController struct {
 	RedeemCodeRepo repository.RedeemCodeRepository
 	PrizeRepo      repository.PrizeRepository
+	CampaignRepo   repository.CampaignRepository
+	CodeBatchRepo  repository.CodeBatchRepository
 }
 
-func NewRedeemCodeController(redeemCodeRepo repository.RedeemCodeRepository, prizeRepo repository.PrizeRepository) *RedeemCodeController {
+func NewRedeemCodeController(redeemCodeRepo repository.RedeemCodeRepository, prizeRepo repository.PrizeRepository, campaignRepo repository.CampaignRepository, codeBatchRepo repository.CodeBatchRepository) *RedeemCodeController {
 	return &RedeemCodeController{
 		RedeemCodeRepo: redeemCodeRepo,
 		PrizeRepo:      prizeRepo,
+		CampaignRepo:   campaignRepo,
+		CodeBatchRepo:  codeBatchRepo,
 	}
 }
 
 func (c *RedeemCodeController) GenerateCode(ctx *gin.Context) {
-	code := utils.GenerateUniqueCode()
+	campaignID, err := strconv.Atoi(ctx.Query("campaign_id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid campaign ID"})
+		return
+	}
+
+	campaign, err := c.CampaignRepo.GetCampaignByID(uint(campaignID))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+		return
+	}
+
+	maxUses, _ := strconv.Atoi(ctx.DefaultQuery("max_uses", "1"))
+	perIdentityLimit, _ := strconv.Atoi(ctx.DefaultQuery("per_identity_limit", "1"))
+	if err := validateUsageLimits(maxUses, perIdentityLimit); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	spec := campaign.CodeSpec()
+	code, err := utils.GenerateUniqueCode(spec, c.RedeemCodeRepo.CodeExists)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate code"})
+		return
+	}
 
+	// Kode satuan berlaku selama kampanyenya berjalan
 	redeemCode := &model.RedeemCode{
-		Code:       code,
-		IsRedeemed: false,
-		Name:       "",
-		NoKTP:      "",
-		City:       "",
-		Address:    "",
-		PhoneNo:    "",
+		CampaignID:       campaign.ID,
+		Code:             code,
+		ValidFrom:        &campaign.StartAt,
+		ValidUntil:       &campaign.EndAt,
+		Status:           model.CodeStatusActive,
+		MaxUses:          maxUses,
+		PerIdentityLimit: perIdentityLimit,
 	}
 
-	err := c.RedeemCodeRepo.SaveRedeemCode(redeemCode)
+	err = c.RedeemCodeRepo.SaveRedeemCode(redeemCode)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save code"})
 		return
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{
-		"code": code,
+		"code": spec.Format(code),
 	})
 }
 
-func (c RedeemCodeController) RedeemCode(ctx *gin.Context) {
-	var redeemCode model.RedeemCode
+// GenerateCodeBatch membuat banyak kode sekaligus dan mencatatnya sebagai satu CodeBatch
+func (c *RedeemCodeController) GenerateCodeBatch(ctx *gin.Context) {
+	var request struct {
+		Count      int             `json:"count"`
+		CampaignID uint            `json:"campaign_id"`
+		Label      string          `json:"label"`
+		CodeFormat codeformat.Spec `json:"code_format"` // opsional, bawaan dari kampanye
+		ValidFrom  *time.Time      `json:"valid_from"`  // opsional, bawaan dari kampanye
+		ValidUntil *time.Time      `json:"valid_until"` // opsional, bawaan dari kampanye
+		// MaxUses dan PerIdentityLimit opsional, bawaannya 1 (kode sekali pakai)
+		MaxUses          int `json:"max_uses"`
+		PerIdentityLimit int `json:"per_identity_limit"`
+	}
 
-	if err := ctx.ShouldBindJSON(&redeemCode); err != nil {
+	if err := ctx.ShouldBindJSON(&request); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
 		return
 	}
 
-	// Memeriksa apakah semua data terisi
-	if redeemCode.Name == "" || redeemCode.NoKTP == "" || redeemCode.City == "" || redeemCode.Address == "" || redeemCode.PhoneNo == "" {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "All fields are required"})
+	if request.Count <= 0 || request.Count > maxBatchCodeCount {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("count must be between 1 and %d", maxBatchCodeCount)})
 		return
 	}
 
-	existingRedeemCode, err := c.RedeemCodeRepo.GetRedeemCodeByCode(redeemCode.Code)
+	campaign, err := c.CampaignRepo.GetCampaignByID(request.CampaignID)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid redeem code"})
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+		return
+	}
+
+	if request.MaxUses == 0 {
+		request.MaxUses = 1
+	}
+	if request.PerIdentityLimit == 0 {
+		request.PerIdentityLimit = 1
+	}
+	if err := validateUsageLimits(request.MaxUses, request.PerIdentityLimit); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	spec := request.CodeFormat.OrDefault(campaign.CodeSpec())
+	if err := spec.Validate(); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if request.ValidFrom == nil {
+		request.ValidFrom = &campaign.StartAt
+	}
+	if request.ValidUntil == nil {
+		request.ValidUntil = &campaign.EndAt
+	}
+	if err := validateValidityWindow(campaign, request.ValidFrom, request.ValidUntil); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	batch := model.CodeBatch{
+		CampaignID:       campaign.ID,
+		Label:            request.Label,
+		Count:            request.Count,
+		CreatedBy:        currentUserID(ctx),
+		CodeFormat:       spec,
+		ValidFrom:        request.ValidFrom,
+		ValidUntil:       request.ValidUntil,
+		MaxUses:          request.MaxUses,
+		PerIdentityLimit: request.PerIdentityLimit,
+	}
+
+	if err := c.CodeBatchRepo.CreateCodeBatch(&batch); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate codes"})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{
+		"batch_id": batch.ID,
+		"count":    batch.Count,
+	})
+}
+
+// validateUsageLimits memeriksa batas penukaran kode multi-pakai
+func validateUsageLimits(maxUses, perIdentityLimit int) error {
+	if maxUses < 1 {
+		return errors.New("max_uses must be at least 1")
+	}
+	if perIdentityLimit < 1 || perIdentityLimit > maxUses {
+		return errors.New("per_identity_limit must be between 1 and max_uses")
+	}
+	return nil
+}
+
+// validateValidityWindow memastikan masa berlaku batch berada di dalam periode kampanye
+func validateValidityWindow(campaign model.Campaign, validFrom, validUntil *time.Time) error {
+	if validFrom != nil && validUntil != nil && !validUntil.After(*validFrom) {
+		return errors.New("valid_until must be after valid_from")
+	}
+	if validFrom != nil && validFrom.Before(campaign.StartAt) {
+		return errors.New("valid_from must not be before the campaign start_at")
+	}
+	if validUntil != nil && validUntil.After(campaign.EndAt) {
+		return errors.New("valid_until must not be after the campaign end_at")
+	}
+	return nil
+}
+
+// UpdateBatchValidity memperpanjang atau memperpendek masa berlaku semua kode dalam satu batch
+func (c *RedeemCodeController) UpdateBatchValidity(ctx *gin.Context) {
+	var request struct {
+		ValidFrom  *time.Time `json:"valid_from"`
+		ValidUntil *time.Time `json:"valid_until"`
+	}
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
 		return
 	}
 
-	// Validasi apakah redeem code sudah digunakan sebelumnya
-	if existingRedeemCode.IsRedeemed {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Redeem code has already been redeemed"})
+	batch, ok := c.batchFromParam(ctx)
+	if !ok {
 		return
 	}
 
-	// Lakukan tindakan yang sesuai dengan kode redeem yang valid dan informasi pengguna
-	existingRedeemCode.Name = redeemCode.Name
-	existingRedeemCode.NoKTP = redeemCode.NoKTP
-	existingRedeemCode.City = redeemCode.City
-	existingRedeemCode.Address = redeemCode.Address
-	existingRedeemCode.PhoneNo = redeemCode.PhoneNo
+	// Field yang tidak diisi tetap memakai nilai lama
+	if request.ValidFrom != nil {
+		batch.ValidFrom = request.ValidFrom
+	}
+	if request.ValidUntil != nil {
+		batch.ValidUntil = request.ValidUntil
+	}
 
-	// Mendapatkan hadiah secara acak dari database
-	randomPrize, err := c.PrizeRepo.GetRandomPrize()
+	campaign, err := c.CampaignRepo.GetCampaignByID(batch.CampaignID)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get random prize"})
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+		return
+	}
+	if err := validateValidityWindow(campaign, batch.ValidFrom, batch.ValidUntil); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Jika stok hadiah tersedia, kurangi stok dan update prize
-	if randomPrize.Quantity > 0 {
-		randomPrize.Quantity--
-		err = c.PrizeRepo.UpdatePrize(&randomPrize)
-		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update prize quantity"})
-			return
-		}
-	} else {
-		// Jika stok hadiah tidak tersedia, set ID hadiah sebagai 0
-		randomPrize.ID = 0
+	if err := c.CodeBatchRepo.UpdateCodeBatchValidity(batch); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update batch validity"})
+		return
 	}
 
-	// Set ID hadiah yang diberikan kepada pengguna ke dalam kolom RedeemCode
-	existingRedeemCode.PrizeID = randomPrize.ID
+	ctx.JSON(http.StatusOK, gin.H{"message": "Batch validity updated successfully", "batch": batch})
+}
 
-	// Menandai redeem code sebagai sudah diredeem
-	existingRedeemCode.IsRedeemed = true
+type revocationRequest struct {
+	Reason string `json:"reason"`
+}
+
+// RevokeCode mencabut satu kode, misalnya karena kodenya bocor
+func (c *RedeemCodeController) RevokeCode(ctx *gin.Context) {
+	var request revocationRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil || request.Reason == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "reason is required"})
+		return
+	}
 
-	// Simpan perubahan ke dalam database
-	err = c.RedeemCodeRepo.UpdateRedeemCode(existingRedeemCode)
+	err := c.withCodeCandidates(ctx.Param("code"), func(code string) error {
+		return c.RedeemCodeRepo.RevokeCode(code, request.Reason, currentUserID(ctx))
+	})
 	if err != nil {
+		revocationError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Redeem code revoked successfully"})
+}
+
+// UnrevokeCode membatalkan pencabutan satu kode
+func (c *RedeemCodeController) UnrevokeCode(ctx *gin.Context) {
+	var request revocationRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	err := c.withCodeCandidates(ctx.Param("code"), func(code string) error {
+		return c.RedeemCodeRepo.UnrevokeCode(code, request.Reason, currentUserID(ctx))
+	})
+	if err != nil {
+		revocationError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Redeem code restored successfully"})
+}
+
+// RevokeBatch mencabut semua kode yang belum ditukarkan dalam satu batch
+func (c *RedeemCodeController) RevokeBatch(ctx *gin.Context) {
+	var request revocationRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil || request.Reason == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "reason is required"})
+		return
+	}
+
+	batch, ok := c.batchFromParam(ctx)
+	if !ok {
+		return
+	}
+
+	affected, err := c.RedeemCodeRepo.RevokeBatch(batch.ID, request.Reason, currentUserID(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke batch"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Batch revoked successfully", "revoked": affected})
+}
+
+// UnrevokeBatch membatalkan pencabutan semua kode dalam satu batch
+func (c *RedeemCodeController) UnrevokeBatch(ctx *gin.Context) {
+	var request revocationRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	batch, ok := c.batchFromParam(ctx)
+	if !ok {
+		return
+	}
+
+	affected, err := c.RedeemCodeRepo.UnrevokeBatch(batch.ID, request.Reason, currentUserID(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore batch"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Batch restored successfully", "restored": affected})
+}
+
+// batchFromParam mengambil batch dari parameter :batch, atau menulis respons error
+func (c *RedeemCodeController) batchFromParam(ctx *gin.Context) (model.CodeBatch, bool) {
+	batchID, err := strconv.Atoi(ctx.Param("batch"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid batch ID"})
+		return model.CodeBatch{}, false
+	}
+
+	batch, err := c.CodeBatchRepo.GetCodeBatchByID(uint(batchID))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Batch not found"})
+		return model.CodeBatch{}, false
+	}
+	return batch, true
+}
+
+func revocationError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repository.ErrRedeemCodeNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Redeem code not found"})
+	case errors.Is(err, repository.ErrRedeemCodeNotRevocable):
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update redeem code"})
+	}
+}
+
+// withCodeCandidates menjalankan fn untuk setiap bentuk kanonik input sampai
+// kodenya ditemukan, dengan normalisasi yang sama seperti saat penukaran
+func (c *RedeemCodeController) withCodeCandidates(input string, fn func(code string) error) error {
+	candidates, err := c.codeCandidates(input)
+	if err != nil {
+		return err
+	}
+	err = repository.ErrRedeemCodeNotFound
+	for _, code := range candidates {
+		err = fn(code)
+		if !errors.Is(err, repository.ErrRedeemCodeNotFound) {
+			break
+		}
+	}
+	return err
+}
+
+// redeemRequest adalah payload penukaran kode oleh peserta
+type redeemRequest struct {
+	Code    string `json:"code"`
+	Name    string `json:"name"`
+	NoKTP   string `json:"no_ktp"`
+	City    string `json:"city"`
+	Address string `json:"address"`
+	PhoneNo string `json:"phone_no"`
+}
+
+func (c RedeemCodeController) RedeemCode(ctx *gin.Context) {
+	var redeemCode redeemRequest
+
+	if err := ctx.ShouldBindJSON(&redeemCode); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload", "error_code": RedeemErrInvalidRequest})
+		return
+	}
+
+	// Memeriksa apakah semua data terisi
+	if redeemCode.Name == "" || redeemCode.NoKTP == "" || redeemCode.City == "" || redeemCode.Address == "" || redeemCode.PhoneNo == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "All fields are required", "error_code": RedeemErrMissingFields})
+		return
+	}
+
+	nikInfo, err := nik.Parse(redeemCode.NoKTP, time.Now())
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "error_code": RedeemErrInvalidNIK})
+		return
+	}
+
+	phoneNo, err := phone.Normalize(redeemCode.PhoneNo)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "error_code": RedeemErrInvalidPhone})
+		return
+	}
+
+	// Kode dinormalisasi dan diperiksa terhadap format kode yang dikenal
+	// sebelum mencari kode di database, sehingga salah ketik langsung ditolak.
+	// Status kampanye pemilik kode diperiksa oleh Redeem.
+	candidates, err := c.codeCandidates(redeemCode.Code)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to redeem code", "error_code": RedeemErrInternal})
+		return
+	}
+	if len(candidates) == 0 {
+		markInvalidAttempt(ctx)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid redeem code format", "error_code": RedeemErrMalformedCode})
+		return
+	}
+
+	// Penukaran kode, pengundian hadiah dan pengurangan stok dilakukan
+	// dalam satu transaksi di repository
+	redemption := model.Redemption{
+		Name:          redeemCode.Name,
+		NoKTP:         nikInfo.NIK,
+		City:          redeemCode.City,
+		Address:       redeemCode.Address,
+		PhoneNo:       phoneNo.E164,
+		PhoneOperator: phoneNo.Operator,
+		ClientIP:      ctx.ClientIP(),
+		UserAgent:     ctx.Request.UserAgent(),
+
+		ProvinceCode: nikInfo.ProvinceCode,
+		Province:     nikInfo.Province,
+		RegencyCode:  nikInfo.RegencyCode,
+		Regency:      nikInfo.Regency,
+		CityMismatch: !nikInfo.MatchesCity(redeemCode.City),
+		IsCity:       nikInfo.IsCity,
+		DistrictCode: nikInfo.DistrictCode,
+		Gender:       nikInfo.Gender,
+		BirthDate:    &nikInfo.BirthDate,
+	}
+	var randomPrize model.Prize
+	for _, code := range candidates {
+		randomPrize, err = c.RedeemCodeRepo.Redeem(code, &redemption)
+		if !errors.Is(err, repository.ErrRedeemCodeNotFound) {
+			break
+		}
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrRedeemCodeNotFound):
+			markInvalidAttempt(ctx)
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid redeem code", "error_code": RedeemErrCodeNotFound})
+		case errors.Is(err, repository.ErrRedeemCodeAlreadyRedeemed):
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Redeem code has already been redeemed", "error_code": RedeemErrAlreadyRedeemed})
+		case errors.Is(err, repository.ErrRedeemCodeNotYetValid):
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Redeem code is not valid yet", "error_code": RedeemErrCodeNotYetValid})
+		case errors.Is(err, repository.ErrRedeemCodeExpired):
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Redeem code has expired", "error_code": RedeemErrCodeExpired})
+		case errors.Is(err, repository.ErrRedemptionLimitReached):
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Redemption limit for this participant has been reached", "error_code": RedeemErrLimitReached})
+		case errors.Is(err, repository.ErrRedeemCodeRevoked):
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Redeem code has been revoked", "error_code": RedeemErrCodeRevoked})
+		case errors.Is(err, repository.ErrCampaignNotActive):
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Campaign is not active", "error_code": RedeemErrCampaignNotActive})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to redeem code", "error_code": RedeemErrInternal})
+		}
+		return
+	}
+
+	// Prize dengan ID 0 berarti hasil undian "tidak dapat hadiah / coba lagi"
+	if randomPrize.ID == 0 {
+		ctx.JSON(http.StatusOK, gin.H{
+			"message":       "Redeem code successfully validated, no prize this time",
+			"result":        "no_prize",
+			"prize":         nil,
+			"redemption_id": redemption.ID,
+		})
 		return
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{
-		"message": "Redeem code successfully validated and marked as redeemed",
-		"prize":   randomPrize,
+		"message":       "Redeem code successfully validated and marked as redeemed",
+		"result":        "prize",
+		"prize":         randomPrize,
+		"redemption_id": redemption.ID,
 	})
 
 }
 
+// codeCandidates mengembalikan bentuk kanonik input untuk setiap format kode
+// yang dikenal dan cocok dengan input tersebut
+func (c RedeemCodeController) codeCandidates(input string) ([]string, error) {
+	specs, err := c.CampaignRepo.GetCodeFormats()
+	if err != nil {
+		return nil, err
+	}
+	return normalizeCode(specs, input), nil
+}
+
+// normalizeCode mengembalikan bentuk kanonik input untuk setiap format pada
+// specs yang cocok dengan input tersebut
+func normalizeCode(specs []codeformat.Spec, input string) []string {
+	var candidates []string
+	seen := make(map[string]bool)
+	for _, spec := range specs {
+		code := spec.Normalize(input)
+		if seen[code] || spec.Verify(code) != nil {
+			continue
+		}
+		seen[code] = true
+		candidates = append(candidates, code)
+	}
+	return candidates
+}
+
 func (c *RedeemCodeController) GetAllRedeems(ctx *gin.Context) {
 
 	redeems, err := c.RedeemCodeRepo.GetAllRedeems()
@@ -0,0 +1,333 @@
+package controller
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gamaput/go-redeem/model"
+	"github.com/gamaput/go-redeem/nik"
+	"github.com/gamaput/go-redeem/phone"
+	"github.com/gamaput/go-redeem/repository"
+	"github.com/gamaput/go-redeem/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// RedemptionController : represent the redemption's controller contract
+type RedemptionController interface {
+	GetAllRedemptions(*gin.Context)
+	GetRedemptionByID(*gin.Context)
+	GetAllParticipants(*gin.Context)
+	GetParticipantByID(*gin.Context)
+	ImportParticipants(*gin.Context)
+	LookupRedemptionStatus(*gin.Context)
+	TransitionFulfillment(*gin.Context)
+	GetFulfillmentHistory(*gin.Context)
+}
+
+type redemptionController struct {
+	redemptionRepo  repository.RedemptionRepository
+	participantRepo repository.ParticipantRepository
+	campaignRepo    repository.CampaignRepository
+	prizeRepo       repository.PrizeRepository
+}
+
+// NewRedemptionController -> returns new redemption controller
+func NewRedemptionController(redemptionRepo repository.RedemptionRepository, participantRepo repository.ParticipantRepository, campaignRepo repository.CampaignRepository, prizeRepo repository.PrizeRepository) RedemptionController {
+	return redemptionController{
+		redemptionRepo:  redemptionRepo,
+		participantRepo: participantRepo,
+		campaignRepo:    campaignRepo,
+		prizeRepo:       prizeRepo,
+	}
+}
+
+// GetAllRedemptions mengembalikan riwayat penukaran, bisa difilter dengan
+// query redeem_code_id, participant_id, fulfillment_status dan
+// city_mismatch=true untuk redemption yang perlu ditinjau
+func (rc redemptionController) GetAllRedemptions(c *gin.Context) {
+	var filter repository.RedemptionFilter
+	if id, err := strconv.Atoi(c.Query("redeem_code_id")); err == nil {
+		filter.RedeemCodeID = uint(id)
+	}
+	if id, err := strconv.Atoi(c.Query("participant_id")); err == nil {
+		filter.ParticipantID = uint(id)
+	}
+	filter.FulfillmentStatus = c.Query("fulfillment_status")
+	filter.CityMismatch = c.Query("city_mismatch") == "true"
+
+	redemptions, err := rc.redemptionRepo.GetAllRedemptions(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, redemptions)
+}
+
+func (rc redemptionController) GetRedemptionByID(c *gin.Context) {
+	id := c.Param("redemption")
+	intID, err := strconv.Atoi(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid redemption ID"})
+		return
+	}
+
+	redemption, err := rc.redemptionRepo.GetRedemptionByID(uint(intID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Redemption not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, redemption)
+}
+
+// TransitionFulfillment memindahkan status pemenuhan hadiah sebuah redemption.
+// Kurir dan nomor resi wajib dikirim saat status menjadi shipped.
+func (rc redemptionController) TransitionFulfillment(c *gin.Context) {
+	intID, err := strconv.Atoi(c.Param("redemption"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid redemption ID"})
+		return
+	}
+
+	var request struct {
+		Status         string `json:"status"`
+		Courier        string `json:"courier"`
+		TrackingNumber string `json:"tracking_number"`
+		Note           string `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+	if !model.IsValidFulfillmentStatus(request.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid fulfillment status"})
+		return
+	}
+
+	redemption, err := rc.redemptionRepo.TransitionFulfillment(uint(intID), model.FulfillmentTransition{
+		ToStatus:       request.Status,
+		Courier:        strings.TrimSpace(request.Courier),
+		TrackingNumber: strings.TrimSpace(request.TrackingNumber),
+		Note:           request.Note,
+		ActorID:        currentUserID(c),
+	})
+	switch {
+	case errors.Is(err, repository.ErrRedemptionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Redemption not found"})
+		return
+	case errors.Is(err, repository.ErrFulfillmentNotApplicable),
+		errors.Is(err, repository.ErrInvalidFulfillmentTransition):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, repository.ErrShipmentDetailsRequired):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update fulfillment status"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Fulfillment status updated successfully", "redemption": redemption})
+}
+
+// GetFulfillmentHistory mengembalikan riwayat status pemenuhan hadiah sebuah redemption
+func (rc redemptionController) GetFulfillmentHistory(c *gin.Context) {
+	intID, err := strconv.Atoi(c.Param("redemption"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid redemption ID"})
+		return
+	}
+	if _, err := rc.redemptionRepo.GetRedemptionByID(uint(intID)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Redemption not found"})
+		return
+	}
+
+	history, err := rc.redemptionRepo.GetFulfillmentHistory(uint(intID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
+func (rc redemptionController) GetAllParticipants(c *gin.Context) {
+
+	participants, err := rc.participantRepo.GetAllParticipants()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, participants)
+}
+
+func (rc redemptionController) GetParticipantByID(c *gin.Context) {
+	id := c.Param("participant")
+	intID, err := strconv.Atoi(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid participant ID"})
+		return
+	}
+
+	participant, err := rc.participantRepo.GetParticipantByID(uint(intID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Participant not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, participant)
+}
+
+// ImportParticipants menyimpan daftar peserta dari sistem lain. NIK dan nomor
+// telepon setiap baris divalidasi dan dinormalkan; jika ada baris yang tidak
+// valid, tidak ada peserta yang disimpan.
+func (rc redemptionController) ImportParticipants(c *gin.Context) {
+	var rows []struct {
+		NIK     string `json:"nik"`
+		Name    string `json:"name"`
+		City    string `json:"city"`
+		Address string `json:"address"`
+		PhoneNo string `json:"phone_no"`
+	}
+	if err := c.ShouldBindJSON(&rows); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	now := time.Now()
+	participants := make([]model.Participant, 0, len(rows))
+	rowErrors := make(map[int]string)
+	for i, row := range rows {
+		nikInfo, err := nik.Parse(row.NIK, now)
+		if err != nil {
+			rowErrors[i] = err.Error()
+			continue
+		}
+		phoneNo, err := phone.Normalize(row.PhoneNo)
+		if err != nil {
+			rowErrors[i] = err.Error()
+			continue
+		}
+		participants = append(participants, model.Participant{
+			NIK:           nikInfo.NIK,
+			Name:          row.Name,
+			City:          row.City,
+			Address:       row.Address,
+			PhoneNo:       phoneNo.E164,
+			PhoneOperator: phoneNo.Operator,
+		})
+	}
+	if len(rowErrors) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Some participants are invalid", "rows": rowErrors})
+		return
+	}
+
+	if err := rc.participantRepo.ImportParticipants(participants); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import participants"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Participants imported successfully", "imported": len(participants)})
+}
+
+// LookupRedemptionStatus adalah endpoint publik bagi peserta untuk melihat
+// kembali hasil penukaran dan status pengiriman hadiahnya. Peserta harus
+// mengirim kode redeem beserta 4 digit terakhir nomor telepon atau NIK yang
+// dipakai saat penukaran; data pribadi pada respons disamarkan.
+func (rc redemptionController) LookupRedemptionStatus(c *gin.Context) {
+	var request struct {
+		Code  string `json:"code"`
+		Last4 string `json:"last4"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+	request.Last4 = strings.TrimSpace(request.Last4)
+	if request.Code == "" || !isLast4Digits(request.Last4) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "code and last4 (4 digits of phone number or NIK) are required"})
+		return
+	}
+
+	specs, err := rc.campaignRepo.GetCodeFormats()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to look up redemption"})
+		return
+	}
+
+	var matches []model.Redemption
+	for _, code := range normalizeCode(specs, request.Code) {
+		redemptions, err := rc.redemptionRepo.GetRedemptionsByCode(code)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to look up redemption"})
+			return
+		}
+		for _, redemption := range redemptions {
+			if matchesLast4(redemption.PhoneNo, request.Last4) || matchesLast4(redemption.NoKTP, request.Last4) {
+				matches = append(matches, redemption)
+			}
+		}
+	}
+
+	// Kode yang tidak ada dan verifikasi yang salah mendapat respons yang sama
+	// agar endpoint ini tidak bisa dipakai untuk menebak kode
+	if len(matches) == 0 {
+		markInvalidAttempt(c)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Redemption not found"})
+		return
+	}
+
+	results := make([]gin.H, 0, len(matches))
+	for _, redemption := range matches {
+		result := gin.H{
+			"redemption_id":      redemption.ID,
+			"redeemed_at":        redemption.CreatedAt,
+			"result":             "no_prize",
+			"prize":              nil,
+			"fulfillment_status": redemption.FulfillmentStatus,
+			"courier":            redemption.Courier,
+			"tracking_number":    redemption.TrackingNumber,
+			"participant": gin.H{
+				"name":     utils.MaskName(redemption.Name),
+				"no_ktp":   utils.MaskTail(redemption.NoKTP, 4),
+				"phone_no": utils.MaskPhone(redemption.PhoneNo),
+				"city":     redemption.City,
+			},
+		}
+		if redemption.PrizeID != 0 {
+			result["result"] = "prize"
+			if prize, err := rc.prizeRepo.GetPrizeByID(redemption.PrizeID); err == nil {
+				result["prize"] = gin.H{"id": prize.ID, "name": prize.Name}
+			}
+		}
+		results = append(results, result)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"redemptions": results})
+}
+
+func isLast4Digits(value string) bool {
+	if len(value) != 4 {
+		return false
+	}
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesLast4 membandingkan 4 digit terakhir value dengan last4 dalam waktu konstan
+func matchesLast4(value, last4 string) bool {
+	if len(value) < 4 {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(value[len(value)-4:]), []byte(last4)) == 1
+}
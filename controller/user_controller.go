@@ -1,37 +1,85 @@
 package controller
 
 import (
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/casbin/casbin/v2"
+	"github.com/gamaput/go-redeem/mailer"
 	"github.com/gamaput/go-redeem/model"
 	"github.com/gamaput/go-redeem/repository"
 	"github.com/gamaput/go-redeem/utils"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // UserController : represent the user's controller contract
 type UserController interface {
-	AddUser(enforcer *casbin.Enforcer) gin.HandlerFunc
+	AddUser(enforcer *casbin.Enforcer, defaultRole string) gin.HandlerFunc
 	GetUser(*gin.Context)
 	GetAllUser(*gin.Context)
-	SignInUser(*gin.Context)
+	SignInUser(enforcer *casbin.Enforcer) gin.HandlerFunc
 	UpdateUser(*gin.Context)
+	ChangeUserRole(enforcer *casbin.Enforcer) gin.HandlerFunc
+	GetUserRoleGrants(*gin.Context)
+	GetRoleAudit(enforcer *casbin.Enforcer, defaultRole string) gin.HandlerFunc
 	DeleteUser(*gin.Context)
 	Logout(*gin.Context)
+	RefreshToken(*gin.Context)
+	GetMySessions(*gin.Context)
+	RevokeMySession(*gin.Context)
+	GetUserSessions(*gin.Context)
+	RevokeUserSessions(*gin.Context)
+	ForgotPassword(*gin.Context)
+	ResetPassword(*gin.Context)
+	VerifyEmail(*gin.Context)
+	ResendVerification(*gin.Context)
+	VerifyMFA(*gin.Context)
+	BeginMFAEnrollment(*gin.Context)
+	GetMFAStatus(*gin.Context)
+	EnrollTOTP(*gin.Context)
+	ConfirmTOTP(*gin.Context)
+	RegenerateRecoveryCodes(*gin.Context)
+	DisableTOTP(enforcer *casbin.Enforcer) gin.HandlerFunc
+	GetLoginLockouts(*gin.Context)
+	GetUserLockoutEvents(*gin.Context)
+	UnlockLogin(*gin.Context)
 }
 
+const (
+	// refreshTokenTTL adalah masa berlaku sesi sejak refresh token terakhir dipakai
+	refreshTokenTTL = 30 * 24 * time.Hour
+	// passwordResetTTL adalah masa berlaku tautan reset password
+	passwordResetTTL = time.Hour
+	// emailVerificationTTL adalah masa berlaku tautan verifikasi email
+	emailVerificationTTL = 48 * time.Hour
+)
+
 type userController struct {
-	userRepo repository.UserRepository
+	userRepo    repository.UserRepository
+	sessionRepo repository.SessionRepository
+	mfaRepo     repository.MFARepository
+	loginRepo   repository.LoginThrottleRepository
+	mailer      mailer.Mailer
+	appURL      string // URL frontend untuk tautan di email, misalnya http://localhost:3000
 }
 
 // NewUserController -> returns new user controller
-func NewUserController(repo repository.UserRepository) UserController {
+func NewUserController(repo repository.UserRepository, sessionRepo repository.SessionRepository, mfaRepo repository.MFARepository, loginRepo repository.LoginThrottleRepository, mail mailer.Mailer, appURL string) UserController {
 	return userController{
-		userRepo: repo,
+		userRepo:    repo,
+		sessionRepo: sessionRepo,
+		mfaRepo:     mfaRepo,
+		loginRepo:   loginRepo,
+		mailer:      mail,
+		appURL:      strings.TrimRight(appURL, "/"),
 	}
 }
 
@@ -64,45 +112,282 @@ func (h userController) GetUser(ctx *gin.Context) {
 
 }
 
-func (h userController) SignInUser(ctx *gin.Context) {
-	var user model.User
-	if err := ctx.ShouldBindJSON(&user); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+// SignInUser memeriksa email dan password. Email yang tidak terdaftar dan
+// password yang salah mendapat respons 401 yang sama; terlalu banyak kegagalan
+// per akun atau per IP mengunci sign in sementara, juga dengan respons 401
+// yang sama. Jika user mengaktifkan 2FA,
+// atau policy mewajibkan 2FA untuk role-nya, yang dikembalikan adalah
+// challenge token yang harus ditukar di /api/signin/mfa dengan kode TOTP.
+func (h userController) SignInUser(enforcer *casbin.Enforcer) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var user model.User
+		if err := ctx.ShouldBindJSON(&user); err != nil || user.Email == "" || user.Password == "" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "email and password are required"})
+			return
+		}
+
+		now := time.Now()
+		attempt := repository.LoginAttempt{Email: user.Email, ClientIP: ctx.ClientIP()}
+		locked, err := h.loginRepo.LoginLockedFor(attempt, now)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in"})
+			return
+		}
+		if locked > 0 {
+			// Password tetap dibandingkan agar waktu respons sama dengan kegagalan biasa
+			utils.ComparePassword(dummyPasswordHash, user.Password)
+			respondInvalidCredentials(ctx)
+			return
+		}
+
+		dbUser, err := h.userRepo.GetByEmail(user.Email)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in"})
+			return
+		}
+		found := err == nil
+		passwordHash := dummyPasswordHash
+		if found {
+			passwordHash = dbUser.Password
+		}
+		if isTrue := utils.ComparePassword(passwordHash, user.Password); !isTrue || !found {
+			attempt.UserID = dbUser.ID
+			if _, err := h.loginRepo.RecordLoginFailure(attempt, now); err != nil {
+				log.Println("failed to record sign in failure:", err)
+			}
+			respondInvalidCredentials(ctx)
+			return
+		}
+		if err := h.loginRepo.RecordLoginSuccess(attempt); err != nil {
+			log.Println("failed to reset sign in failures:", err)
+		}
+
+		userTOTP, err := h.mfaRepo.GetTOTP(dbUser.ID)
+		if err != nil && !errors.Is(err, repository.ErrTOTPNotEnrolled) {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in"})
+			return
+		}
+		enabled := err == nil && userTOTP.ConfirmedAt != nil
+		required, err := mfaRequired(enforcer, dbUser.ID)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in"})
+			return
+		}
+		if enabled || required {
+			mfaToken, err := utils.GenerateMFAToken(dbUser.ID)
+			if err != nil {
+				ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+				return
+			}
+			ctx.JSON(http.StatusOK, gin.H{"msg": "Two-factor authentication required", "mfa_required": true,
+				"mfa_enrollment_required": !enabled, "mfa_token": mfaToken, "expires_in": int(utils.MFATokenTTL.Seconds())})
+			return
+		}
+
+		h.startSession(ctx, dbUser, nil)
 	}
+}
 
-	dbUser, err := h.userRepo.GetByEmail(user.Email)
+// startSession membuat sesi baru untuk user yang sudah terautentikasi dan
+// mengirim access token serta refresh token, ditambah field extra
+func (h userController) startSession(ctx *gin.Context, dbUser model.User, extra gin.H) {
+	ctx.Set("userID", dbUser.ID)
+	refreshToken, err := utils.RandomToken(32)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create session"})
+		return
+	}
+	now := time.Now()
+	session := model.Session{
+		UserID:     dbUser.ID,
+		ClientIP:   ctx.ClientIP(),
+		UserAgent:  ctx.Request.UserAgent(),
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(refreshTokenTTL),
+	}
+	if err := h.sessionRepo.CreateSession(&session, refreshToken); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create session"})
+		return
+	}
+	token, err := utils.GenerateToken(dbUser.ID, session.ID)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"msg": "No Such User Found"})
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
+	}
+
+	ctx.Writer.Header().Set("Authorization", "Bearer "+token)
 
+	response := gin.H{"msg": "Successfully SignIN", "id": dbUser.ID, "name": dbUser.Name, "email": dbUser.Email, "token": token, "role": dbUser.Role,
+		"refresh_token": refreshToken, "expires_in": int(utils.AccessTokenTTL.Seconds())}
+	for key, value := range extra {
+		response[key] = value
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+// Logout mencabut sesi dari access token yang dipakai, sehingga access token
+// dan refresh token sesi tersebut tidak berlaku lagi. Client yang access
+// token-nya sudah kedaluwarsa dapat mengirim refresh_token di body.
+func (h userController) Logout(ctx *gin.Context) {
+	var request struct {
+		RefreshToken string `json:"refresh_token"`
 	}
-	if isTrue := utils.ComparePassword(dbUser.Password, user.Password); isTrue {
-		fmt.Println("user before", dbUser.ID)
-		ctx.Set("userID", dbUser.ID)
-		token := utils.GenerateToken(dbUser.ID)
+	// Body boleh kosong jika access token dipakai
+	_ = ctx.ShouldBindJSON(&request)
 
-		ctx.Writer.Header().Set("Authorization", "Bearer "+token)
+	var err error
+	switch {
+	case request.RefreshToken != "":
+		err = h.sessionRepo.RevokeSessionByRefreshToken(request.RefreshToken, repository.SessionRevokedLogout)
+		if errors.Is(err, repository.ErrRefreshTokenInvalid) {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+	case currentSessionID(ctx) != 0:
+		err = h.sessionRepo.RevokeSession(currentUserID(ctx), currentSessionID(ctx), repository.SessionRevokedLogout)
+	default:
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "A valid access token or refresh_token is required"})
+		return
+	}
+	if err != nil && !errors.Is(err, repository.ErrSessionNotFound) {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+	ctx.Writer.Header().Del("Authorization")
+	ctx.JSON(http.StatusOK, gin.H{"msg": "Successfully logged out"})
+}
 
-		ctx.JSON(http.StatusOK, gin.H{"msg": "Successfully SignIN", "id": dbUser.ID, "name": dbUser.Name, "email": dbUser.Email, "token": token, "role": dbUser.Role})
+// RefreshToken menukar refresh token dengan access token dan refresh token
+// baru. Refresh token lama tidak bisa dipakai lagi; jika dipakai lagi, seluruh
+// sesinya dicabut.
+func (h userController) RefreshToken(ctx *gin.Context) {
+	var request struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := ctx.ShouldBindJSON(&request); err != nil || request.RefreshToken == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "refresh_token is required"})
+		return
+	}
 
+	newRefreshToken, err := utils.RandomToken(32)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
+		return
+	}
+	session, err := h.sessionRepo.RotateRefreshToken(request.RefreshToken, newRefreshToken, refreshTokenTTL)
+	switch {
+	case errors.Is(err, repository.ErrRefreshTokenInvalid), errors.Is(err, repository.ErrRefreshTokenReused):
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
 		return
 	}
-	ctx.JSON(http.StatusInternalServerError, gin.H{"msg": "Password not matched"})
 
+	token, err := utils.GenerateToken(session.UserID, session.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	ctx.Writer.Header().Set("Authorization", "Bearer "+token)
+	ctx.JSON(http.StatusOK, gin.H{"token": token, "refresh_token": newRefreshToken, "expires_in": int(utils.AccessTokenTTL.Seconds())})
 }
 
-func (h userController) Logout(ctx *gin.Context) {
-	ctx.Writer.Header().Del("Authorization")
-	ctx.JSON(http.StatusOK, gin.H{"msg": "Successfully logged out"})
+// GetMySessions mengembalikan sesi aktif milik user yang sedang login
+func (h userController) GetMySessions(ctx *gin.Context) {
+	sessions, err := h.sessionRepo.GetActiveSessions(currentUserID(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"current_session_id": currentSessionID(ctx), "sessions": sessions})
+}
+
+// RevokeMySession mencabut salah satu sesi milik user yang sedang login
+func (h userController) RevokeMySession(ctx *gin.Context) {
+	sessionID, err := strconv.Atoi(ctx.Param("session"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+	if err := h.sessionRepo.RevokeSession(currentUserID(ctx), uint(sessionID), repository.SessionRevokedUser); err != nil {
+		if errors.Is(err, repository.ErrSessionNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"msg": "Session revoked successfully"})
+}
+
+// GetUserSessions mengembalikan sesi aktif milik user lain, untuk admin
+func (h userController) GetUserSessions(ctx *gin.Context) {
+	userID, err := strconv.Atoi(ctx.Param("user"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	sessions, err := h.sessionRepo.GetActiveSessions(uint(userID))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, sessions)
+}
+
+// RevokeUserSessions mencabut satu sesi user (query session_id) atau semua sesinya, untuk admin
+func (h userController) RevokeUserSessions(ctx *gin.Context) {
+	userID, err := strconv.Atoi(ctx.Param("user"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if id := ctx.Query("session_id"); id != "" {
+		sessionID, err := strconv.Atoi(id)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+			return
+		}
+		if err := h.sessionRepo.RevokeSession(uint(userID), uint(sessionID), repository.SessionRevokedAdmin); err != nil {
+			if errors.Is(err, repository.ErrSessionNotFound) {
+				ctx.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+				return
+			}
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"msg": "Session revoked successfully", "revoked": 1})
+		return
+	}
+
+	revoked, err := h.sessionRepo.RevokeAllSessions(uint(userID), repository.SessionRevokedAdmin)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"msg": "Sessions revoked successfully", "revoked": revoked})
 }
 
-func (h userController) AddUser(enforcer *casbin.Enforcer) gin.HandlerFunc {
+// userRequest adalah field user yang boleh diisi lewat body. Role dan ID tidak
+// termasuk: role hanya bisa diubah lewat ChangeUserRole.
+type userRequest struct {
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// AddUser mendaftarkan user baru dengan defaultRole. Role pada body diabaikan,
+// agar pendaftaran publik tidak bisa memberi dirinya sendiri role admin.
+func (h userController) AddUser(enforcer *casbin.Enforcer, defaultRole string) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		var user model.User
-		if err := ctx.ShouldBindJSON(&user); err != nil {
+		var request userRequest
+		if err := ctx.ShouldBindJSON(&request); err != nil {
 			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
+		user := model.User{Name: request.Name, Email: request.Email, Password: request.Password, Role: defaultRole}
 		utils.HashPassword(&user.Password)
 		user, err := h.userRepo.AddUser(user)
 		if err != nil {
@@ -111,15 +396,22 @@ func (h userController) AddUser(enforcer *casbin.Enforcer) gin.HandlerFunc {
 
 		}
 		enforcer.AddGroupingPolicy(fmt.Sprint(user.ID), user.Role)
+		if err := h.sendVerificationEmail(user); err != nil {
+			log.Println("failed to send verification email:", err)
+		}
 		user.Password = ""
 		ctx.JSON(http.StatusOK, user)
 
 	}
 }
 
+// UpdateUser mengubah nama, email, dan password user. Password hanya diubah
+// jika diisi; setelah itu semua sesi user dicabut kecuali sesi yang sedang
+// dipakai jika user mengubah passwordnya sendiri, sehingga refresh token yang
+// dicuri tidak bisa dipakai lagi.
 func (h userController) UpdateUser(ctx *gin.Context) {
-	var user model.User
-	if err := ctx.ShouldBindJSON(&user); err != nil {
+	var request userRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -127,19 +419,278 @@ func (h userController) UpdateUser(ctx *gin.Context) {
 	intID, err := strconv.Atoi(id)
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
+	existing, err := h.userRepo.GetUser(intID)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	user := model.User{Name: request.Name, Email: request.Email, Password: request.Password}
 	user.ID = uint(intID)
-	utils.HashPassword(&user.Password)
+	if user.Password != "" {
+		utils.HashPassword(&user.Password)
+	}
 	user, err = h.userRepo.UpdateUser(user)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 
 	}
+	if request.Password != "" {
+		var keepSessionID uint
+		if currentUserID(ctx) == user.ID {
+			keepSessionID = currentSessionID(ctx)
+		}
+		if _, err := h.sessionRepo.RevokeOtherSessions(user.ID, keepSessionID, repository.SessionRevokedPasswordChange); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Password changed but failed to revoke sessions"})
+			return
+		}
+	}
+	// Email baru harus diverifikasi ulang
+	if user.Email != existing.Email {
+		if err := h.sendVerificationEmail(user); err != nil {
+			log.Println("failed to send verification email:", err)
+		}
+	}
+	user.Password = ""
 	ctx.JSON(http.StatusOK, user)
 
 }
 
+// ChangeUserRole mengganti role user. Role harus sudah ada di policy Casbin,
+// dan admin yang mengubahnya dicatat di riwayat role.
+func (h userController) ChangeUserRole(enforcer *casbin.Enforcer) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userID, err := strconv.Atoi(ctx.Param("user"))
+		if err != nil || userID <= 0 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+			return
+		}
+		var request struct {
+			Role string `json:"role" binding:"required"`
+		}
+		if err := ctx.ShouldBindJSON(&request); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "role is required"})
+			return
+		}
+		grantedBy := currentUserID(ctx)
+		if uint(userID) == grantedBy {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "You cannot change your own role"})
+			return
+		}
+		exists, err := RoleExists(enforcer, request.Role)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load roles"})
+			return
+		}
+		if !exists {
+			ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": fmt.Sprintf("role %q does not exist", request.Role)})
+			return
+		}
+
+		if _, err := h.userRepo.GetUser(userID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				ctx.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+				return
+			}
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Policy Casbin ditulis lebih dulu; jika update database gagal, grouping
+		// lama dikembalikan agar role di database dan Casbin tetap sama
+		subject := strconv.Itoa(userID)
+		previousRoles, err := enforcer.GetRolesForUser(subject)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load role policy"})
+			return
+		}
+		if err := setUserRoles(enforcer, subject, request.Role); err != nil {
+			log.Println("failed to update role policy:", err)
+			if err := setUserRoles(enforcer, subject, previousRoles...); err != nil {
+				log.Printf("failed to restore role policy of user %s: %v", subject, err)
+			}
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role policy"})
+			return
+		}
+		user, err := h.userRepo.ChangeRole(uint(userID), request.Role, grantedBy)
+		if err != nil {
+			if err := setUserRoles(enforcer, subject, previousRoles...); err != nil {
+				log.Printf("failed to restore role policy of user %s: %v", subject, err)
+			}
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				ctx.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+				return
+			}
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusOK, user)
+	}
+}
+
+// GetUserRoleGrants mengembalikan riwayat perubahan role user
+func (h userController) GetUserRoleGrants(ctx *gin.Context) {
+	userID, err := strconv.Atoi(ctx.Param("user"))
+	if err != nil || userID <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+	grants, err := h.userRepo.GetRoleGrants(uint(userID))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, grants)
+}
+
+// setUserRoles mengganti seluruh grouping Casbin milik subject dengan roles
+func setUserRoles(enforcer *casbin.Enforcer, subject string, roles ...string) error {
+	if _, err := enforcer.DeleteRolesForUser(subject); err != nil {
+		return err
+	}
+	if len(roles) == 0 {
+		return nil
+	}
+	_, err := enforcer.AddRolesForUser(subject, roles)
+	return err
+}
+
+// Jenis temuan audit role
+const (
+	// RoleAuditUnconfirmed: role bukan role bawaan dan tidak pernah diberikan
+	// admin lewat API, misalnya dipilih sendiri lewat /api/register versi
+	// lama atau diubah langsung di database
+	RoleAuditUnconfirmed = "role_not_granted_by_admin"
+	// RoleAuditCasbinMismatch: grouping Casbin user berbeda dengan role di database
+	RoleAuditCasbinMismatch = "casbin_roles_mismatch"
+)
+
+// RoleAuditFinding adalah satu user yang role-nya perlu ditinjau admin
+type RoleAuditFinding struct {
+	UserID      uint     `json:"user_id"`
+	Email       string   `json:"email"`
+	Role        string   `json:"role"`
+	CasbinRoles []string `json:"casbin_roles"`
+	Problem     string   `json:"problem"`
+}
+
+// AuditRoles mencari user yang role-nya perlu ditinjau tanpa mengubah apa
+// pun; admin menurunkan role yang tidak sah lewat PUT /api/users/:user/role
+func AuditRoles(enforcer *casbin.Enforcer, userRepo repository.UserRepository, defaultRole string) ([]RoleAuditFinding, error) {
+	if err := enforcer.LoadPolicy(); err != nil {
+		return nil, err
+	}
+	finding := func(user model.User, problem string) (RoleAuditFinding, error) {
+		roles, err := enforcer.GetRolesForUser(fmt.Sprint(user.ID))
+		return RoleAuditFinding{UserID: user.ID, Email: user.Email, Role: user.Role, CasbinRoles: roles, Problem: problem}, err
+	}
+
+	findings := []RoleAuditFinding{}
+	unconfirmed, err := userRepo.GetUnconfirmedRoles(defaultRole)
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range unconfirmed {
+		f, err := finding(user, RoleAuditUnconfirmed)
+		if err != nil {
+			return nil, err
+		}
+		findings = append(findings, f)
+	}
+
+	users, err := userRepo.GetAllUser()
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		f, err := finding(user, RoleAuditCasbinMismatch)
+		if err != nil {
+			return nil, err
+		}
+		if len(f.CasbinRoles) != 1 || f.CasbinRoles[0] != user.Role {
+			findings = append(findings, f)
+		}
+	}
+	return findings, nil
+}
+
+// GetRoleAudit mengembalikan hasil AuditRoles
+func (h userController) GetRoleAudit(enforcer *casbin.Enforcer, defaultRole string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		findings, err := AuditRoles(enforcer, h.userRepo, defaultRole)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to audit roles"})
+			return
+		}
+		ctx.JSON(http.StatusOK, findings)
+	}
+}
+
+// BootstrapAdmin memberikan role kepada user dengan email tersebut selama
+// belum ada user yang memiliki role itu, sehingga instalasi baru bisa
+// mendapatkan admin pertamanya. User harus sudah mendaftar dan memverifikasi
+// emailnya, agar orang lain tidak bisa mendaftar lebih dulu dengan email itu.
+// Seperti ChangeUserRole, policy Casbin ditulis sebelum database.
+func BootstrapAdmin(enforcer *casbin.Enforcer, userRepo repository.UserRepository, email, role string) error {
+	count, err := userRepo.CountUsersWithRole(role)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	user, err := userRepo.GetByEmail(email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("user %s is not registered yet", email)
+	}
+	if err != nil {
+		return err
+	}
+	if user.EmailVerifiedAt == nil {
+		return fmt.Errorf("user %s has not verified their email yet", email)
+	}
+
+	subject := fmt.Sprint(user.ID)
+	previousRoles, err := enforcer.GetRolesForUser(subject)
+	if err != nil {
+		return err
+	}
+	if err := setUserRoles(enforcer, subject, role); err != nil {
+		return err
+	}
+	if _, err := userRepo.BootstrapRole(user.ID, role); err != nil {
+		if err := setUserRoles(enforcer, subject, previousRoles...); err != nil {
+			log.Printf("failed to restore role policy of user %s: %v", subject, err)
+		}
+		return err
+	}
+	log.Printf("[Bootstrap] user %d (%s) is now %q", user.ID, email, role)
+	return nil
+}
+
+// RoleExists mengembalikan true jika role memiliki policy di Casbin. Role yang
+// berupa angka ditolak karena subjek angka adalah ID user.
+func RoleExists(enforcer *casbin.Enforcer, role string) (bool, error) {
+	if role == "" {
+		return false, nil
+	}
+	if _, err := strconv.ParseUint(role, 10, 64); err == nil {
+		return false, nil
+	}
+	if err := enforcer.LoadPolicy(); err != nil {
+		return false, err
+	}
+	for _, subject := range enforcer.GetAllSubjects() {
+		if subject == role {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 func (h userController) DeleteUser(ctx *gin.Context) {
 	var user model.User
 	id := ctx.Param("user")
@@ -154,3 +705,140 @@ func (h userController) DeleteUser(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, user)
 
 }
+
+// ForgotPassword mengirim tautan reset password ke email user. Respons selalu
+// sama, baik email terdaftar maupun tidak, agar tidak bisa dipakai untuk
+// menebak email yang terdaftar.
+func (h userController) ForgotPassword(ctx *gin.Context) {
+	var request struct {
+		Email string `json:"email" binding:"required"`
+	}
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "email is required"})
+		return
+	}
+
+	user, err := h.userRepo.GetByEmail(request.Email)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+	case err != nil:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process request"})
+		return
+	default:
+		token, err := h.issueUserToken(user.ID, model.UserTokenPasswordReset, passwordResetTTL)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process request"})
+			return
+		}
+		h.sendAsync(mailer.Message{
+			To:      user.Email,
+			Subject: "Reset password",
+			Body: fmt.Sprintf("Hi %s,\n\nOpen the link below to choose a new password. The link can be used once and expires in %s.\n\n%s\n\nIf you did not request a password reset, you can ignore this email.",
+				user.Name, passwordResetTTL, h.link("/reset-password", token)),
+		})
+	}
+	ctx.JSON(http.StatusOK, gin.H{"msg": "If the email is registered, a password reset link has been sent"})
+}
+
+// ResetPassword mengganti password dengan token dari email reset password.
+// Semua sesi user dicabut, sehingga user harus sign in lagi.
+func (h userController) ResetPassword(ctx *gin.Context) {
+	var request struct {
+		Token    string `json:"token" binding:"required"`
+		Password string `json:"password" binding:"required,min=8"`
+	}
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "token and password (at least 8 characters) are required"})
+		return
+	}
+
+	utils.HashPassword(&request.Password)
+	if _, err := h.userRepo.ResetPassword(request.Token, request.Password); err != nil {
+		if errors.Is(err, repository.ErrUserTokenInvalid) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"msg": "Password has been reset, please sign in again"})
+}
+
+// VerifyEmail menandai email user sebagai terverifikasi dengan token dari email verifikasi
+func (h userController) VerifyEmail(ctx *gin.Context) {
+	var request struct {
+		Token string `json:"token" binding:"required"`
+	}
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "token is required"})
+		return
+	}
+
+	user, err := h.userRepo.VerifyEmail(request.Token)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserTokenInvalid) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"msg": "Email has been verified", "email_verified_at": user.EmailVerifiedAt})
+}
+
+// ResendVerification mengirim ulang email verifikasi ke user yang sedang login
+func (h userController) ResendVerification(ctx *gin.Context) {
+	user, err := h.userRepo.GetUser(int(currentUserID(ctx)))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if user.EmailVerifiedAt != nil {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "Email is already verified"})
+		return
+	}
+	if err := h.sendVerificationEmail(user); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send verification email"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"msg": "Verification email has been sent"})
+}
+
+// sendVerificationEmail membuat token verifikasi email baru dan mengirimkannya ke user
+func (h userController) sendVerificationEmail(user model.User) error {
+	token, err := h.issueUserToken(user.ID, model.UserTokenEmailVerification, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+	h.sendAsync(mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email",
+		Body: fmt.Sprintf("Hi %s,\n\nOpen the link below to verify your email address. The link expires in %s.\n\n%s",
+			user.Name, emailVerificationTTL, h.link("/verify-email", token)),
+	})
+	return nil
+}
+
+// issueUserToken membuat token acak dan menyimpan hash-nya
+func (h userController) issueUserToken(userID uint, purpose string, ttl time.Duration) (string, error) {
+	token, err := utils.RandomToken(32)
+	if err != nil {
+		return "", err
+	}
+	return token, h.userRepo.CreateUserToken(userID, purpose, token, ttl)
+}
+
+// link mengembalikan tautan frontend dengan token sebagai query parameter
+func (h userController) link(path, token string) string {
+	return h.appURL + path + "?token=" + url.QueryEscape(token)
+}
+
+// sendAsync mengirim email di luar request, agar lambatnya server SMTP tidak
+// menahan respons dan waktu respons tidak membedakan email terdaftar
+func (h userController) sendAsync(msg mailer.Message) {
+	go func() {
+		if err := h.mailer.Send(msg); err != nil {
+			log.Printf("failed to send email %q to %s: %v", msg.Subject, msg.To, err)
+		}
+	}()
+}
@@ -0,0 +1,235 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gamaput/go-redeem/model"
+	"github.com/gamaput/go-redeem/repository"
+	"github.com/gamaput/go-redeem/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// WebhookController : represent the webhook's controller contract
+type WebhookController interface {
+	GetAllSubscriptions(*gin.Context)
+	CreateSubscription(*gin.Context)
+	GetSubscriptionByID(*gin.Context)
+	UpdateSubscription(*gin.Context)
+	DeleteSubscription(*gin.Context)
+	GetDeliveries(*gin.Context)
+	GetDeliveryByID(*gin.Context)
+	RetryDelivery(*gin.Context)
+}
+
+type webhookController struct {
+	webhookRepo repository.WebhookRepository
+}
+
+// NewWebhookController -> returns new webhook controller
+func NewWebhookController(repo repository.WebhookRepository) WebhookController {
+	return webhookController{
+		webhookRepo: repo,
+	}
+}
+
+type subscriptionRequest struct {
+	URL         string   `json:"url"`
+	EventTypes  []string `json:"event_types"`
+	Secret      string   `json:"secret"`
+	Description string   `json:"description"`
+	Active      *bool    `json:"active"`
+}
+
+// validate memeriksa URL dan jenis event, lalu mengembalikan jenis event dalam bentuk yang disimpan
+func (r subscriptionRequest) validate() (string, error) {
+	u, err := url.Parse(r.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", errors.New("url must be an absolute http or https URL")
+	}
+	if len(r.EventTypes) == 0 {
+		return "", errors.New("event_types is required")
+	}
+	for _, eventType := range r.EventTypes {
+		if !model.IsValidWebhookEventType(eventType) {
+			return "", errors.New("unknown event type " + eventType + ", expected one of " + strings.Join(model.WebhookEventTypes, ", "))
+		}
+	}
+	if r.Secret != "" && len(r.Secret) < 16 {
+		return "", errors.New("secret must be at least 16 characters")
+	}
+	return strings.Join(r.EventTypes, ","), nil
+}
+
+func (wc webhookController) GetAllSubscriptions(c *gin.Context) {
+	subscriptions, err := wc.webhookRepo.GetAllSubscriptions()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, subscriptions)
+}
+
+// CreateSubscription menyimpan langganan webhook baru. Jika secret tidak
+// dikirim, secret dibuat acak; secret hanya ditampilkan pada respons ini.
+func (wc webhookController) CreateSubscription(c *gin.Context) {
+	var request subscriptionRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+	eventTypes, err := request.validate()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	secret := request.Secret
+	if secret == "" {
+		if secret, err = utils.RandomToken(32); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate secret"})
+			return
+		}
+	}
+
+	subscription := model.WebhookSubscription{
+		URL:         request.URL,
+		Secret:      secret,
+		EventTypes:  eventTypes,
+		Description: request.Description,
+		Active:      request.Active == nil || *request.Active,
+		CreatedBy:   currentUserID(c),
+	}
+	if err := wc.webhookRepo.CreateSubscription(&subscription); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create subscription"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"subscription": subscription, "secret": secret})
+}
+
+func (wc webhookController) GetSubscriptionByID(c *gin.Context) {
+	subscription, ok := wc.subscriptionFromParam(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, subscription)
+}
+
+// UpdateSubscription memperbarui langganan; secret lama tetap dipakai jika tidak dikirim
+func (wc webhookController) UpdateSubscription(c *gin.Context) {
+	subscription, ok := wc.subscriptionFromParam(c)
+	if !ok {
+		return
+	}
+
+	var request subscriptionRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+	eventTypes, err := request.validate()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	subscription.URL = request.URL
+	subscription.EventTypes = eventTypes
+	subscription.Description = request.Description
+	if request.Secret != "" {
+		subscription.Secret = request.Secret
+	}
+	if request.Active != nil {
+		subscription.Active = *request.Active
+	}
+	if err := wc.webhookRepo.UpdateSubscription(subscription); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update subscription"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Subscription updated successfully", "subscription": subscription})
+}
+
+func (wc webhookController) DeleteSubscription(c *gin.Context) {
+	subscription, ok := wc.subscriptionFromParam(c)
+	if !ok {
+		return
+	}
+	if err := wc.webhookRepo.DeleteSubscription(subscription.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete subscription"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Subscription deleted successfully"})
+}
+
+// GetDeliveries mengembalikan log pengiriman webhook, bisa difilter dengan
+// query subscription_id, event_id dan status
+func (wc webhookController) GetDeliveries(c *gin.Context) {
+	var filter repository.DeliveryFilter
+	if id, err := strconv.Atoi(c.Query("subscription_id")); err == nil {
+		filter.SubscriptionID = uint(id)
+	}
+	if id, err := strconv.Atoi(c.Query("event_id")); err == nil {
+		filter.EventID = uint(id)
+	}
+	filter.Status = c.Query("status")
+
+	deliveries, err := wc.webhookRepo.GetDeliveries(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// GetDeliveryByID mengembalikan satu pengiriman beserta log setiap percobaannya
+func (wc webhookController) GetDeliveryByID(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("delivery"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid delivery ID"})
+		return
+	}
+	delivery, err := wc.webhookRepo.GetDeliveryByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Delivery not found"})
+		return
+	}
+	c.JSON(http.StatusOK, delivery)
+}
+
+// RetryDelivery menjadwalkan ulang pengiriman yang sudah berstatus dead
+func (wc webhookController) RetryDelivery(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("delivery"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid delivery ID"})
+		return
+	}
+	if err := wc.webhookRepo.RetryDelivery(uint(id)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Dead delivery not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retry delivery"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Delivery scheduled for retry"})
+}
+
+func (wc webhookController) subscriptionFromParam(c *gin.Context) (model.WebhookSubscription, bool) {
+	id, err := strconv.Atoi(c.Param("subscription"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid subscription ID"})
+		return model.WebhookSubscription{}, false
+	}
+	subscription, err := wc.webhookRepo.GetSubscriptionByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subscription not found"})
+		return model.WebhookSubscription{}, false
+	}
+	return subscription, true
+}
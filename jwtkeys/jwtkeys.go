@@ -0,0 +1,331 @@
+// Package jwtkeys mengelola key ring untuk menandatangani dan memverifikasi
+// JWT dengan RS256 atau EdDSA. Setiap key memiliki kid; satu key aktif dipakai
+// untuk menandatangani, sedangkan key lama tetap dipakai untuk verifikasi
+// sampai masa tenggangnya (VerifyUntil) berakhir.
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// Algoritma yang didukung
+const (
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+var (
+	// ErrNoSigningKey dikembalikan jika key ring tidak memiliki key aktif dengan private key
+	ErrNoSigningKey = errors.New("jwtkeys: no signing key configured")
+	// ErrUnknownKey dikembalikan jika token memakai kid yang tidak ada atau sudah tidak berlaku
+	ErrUnknownKey = errors.New("jwtkeys: unknown or expired key id")
+)
+
+// Key adalah satu key pada key ring
+type Key struct {
+	ID          string
+	Algorithm   string
+	PrivateKey  crypto.Signer    // nil untuk key yang hanya dipakai verifikasi
+	PublicKey   crypto.PublicKey // *rsa.PublicKey atau ed25519.PublicKey
+	VerifyUntil time.Time        // nol jika key berlaku tanpa batas waktu
+}
+
+func (k Key) method() jwt.SigningMethod {
+	if k.Algorithm == AlgEdDSA {
+		return SigningMethodEdDSA
+	}
+	return jwt.SigningMethodRS256
+}
+
+func (k Key) validAt(now time.Time) bool {
+	return k.VerifyUntil.IsZero() || now.Before(k.VerifyUntil)
+}
+
+// Ring adalah kumpulan key dengan satu key aktif untuk menandatangani
+type Ring struct {
+	active string
+	keys   map[string]Key
+}
+
+// NewRing membuat key ring dengan key aktif activeID. Key aktif harus memiliki
+// private key dan masa tenggangnya belum berakhir.
+func NewRing(activeID string, keys ...Key) (*Ring, error) {
+	ring := &Ring{active: activeID, keys: make(map[string]Key, len(keys))}
+	for _, key := range keys {
+		if key.ID == "" {
+			return nil, errors.New("jwtkeys: key id is required")
+		}
+		if _, ok := ring.keys[key.ID]; ok {
+			return nil, fmt.Errorf("jwtkeys: duplicate key id %q", key.ID)
+		}
+		if err := checkAlgorithm(key); err != nil {
+			return nil, err
+		}
+		ring.keys[key.ID] = key
+	}
+	key, ok := ring.keys[activeID]
+	if !ok || key.PrivateKey == nil {
+		return nil, ErrNoSigningKey
+	}
+	// Token dari key aktif yang sudah lewat VerifyUntil tidak akan lolos verifikasi
+	if !key.validAt(time.Now()) {
+		return nil, fmt.Errorf("%w: active key %q expired at %s", ErrNoSigningKey, key.ID, key.VerifyUntil.Format(time.RFC3339))
+	}
+	return ring, nil
+}
+
+func checkAlgorithm(key Key) error {
+	switch key.PublicKey.(type) {
+	case *rsa.PublicKey:
+		if key.Algorithm == AlgRS256 {
+			return nil
+		}
+	case ed25519.PublicKey:
+		if key.Algorithm == AlgEdDSA {
+			return nil
+		}
+	}
+	return fmt.Errorf("jwtkeys: key %q does not match algorithm %q", key.ID, key.Algorithm)
+}
+
+// Sign menandatangani claims dengan key aktif dan mengisi header kid
+func (r *Ring) Sign(claims jwt.Claims) (string, error) {
+	key := r.keys[r.active]
+	token := jwt.NewWithClaims(key.method(), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.PrivateKey)
+}
+
+// Keyfunc dipakai dengan jwt.Parse untuk memilih public key berdasarkan kid.
+// Token yang algoritmanya berbeda dengan algoritma key-nya ditolak.
+func (r *Ring) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := r.keys[kid]
+	if !ok || !key.validAt(time.Now()) {
+		return nil, ErrUnknownKey
+	}
+	if token.Method.Alg() != key.Algorithm {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.PublicKey, nil
+}
+
+// JWK adalah public key dalam format JSON Web Key (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKSet adalah isi /.well-known/jwks.json
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS mengembalikan public key dari semua key yang masih berlaku
+func (r *Ring) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	now := time.Now()
+	for _, key := range r.keys {
+		if key.validAt(now) {
+			set.Keys = append(set.Keys, toJWK(key))
+		}
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set
+}
+
+func toJWK(key Key) JWK {
+	jwk := JWK{Use: "sig", Alg: key.Algorithm, Kid: key.ID}
+	switch pub := key.PublicKey.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+	}
+	return jwk
+}
+
+// Thumbprint mengembalikan JWK thumbprint SHA-256 (RFC 7638) dari public key,
+// dipakai sebagai kid bawaan
+func Thumbprint(pub crypto.PublicKey) (string, error) {
+	var members interface{}
+	switch jwk := toJWK(Key{PublicKey: pub}); jwk.Kty {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	case "OKP":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	default:
+		return "", errors.New("jwtkeys: unsupported key type")
+	}
+	b, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// ParsePEM membaca private key (PKCS#8 atau PKCS#1) atau public key (PKIX atau
+// PKCS#1) RSA maupun Ed25519 dari PEM, dan mengembalikan key beserta
+// algoritmanya. Private key dapat dibuat dengan
+// "openssl genpkey -algorithm ed25519" atau "openssl genpkey -algorithm rsa".
+func ParsePEM(data []byte) (Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return Key{}, errors.New("jwtkeys: no PEM block found")
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return Key{}, fmt.Errorf("jwtkeys: unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return Key{}, err
+	}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		return Key{Algorithm: AlgRS256, PrivateKey: k, PublicKey: &k.PublicKey}, nil
+	case ed25519.PrivateKey:
+		return Key{Algorithm: AlgEdDSA, PrivateKey: k, PublicKey: k.Public()}, nil
+	case *rsa.PublicKey:
+		return Key{Algorithm: AlgRS256, PublicKey: k}, nil
+	case ed25519.PublicKey:
+		return Key{Algorithm: AlgEdDSA, PublicKey: k}, nil
+	}
+	return Key{}, errors.New("jwtkeys: only RSA and Ed25519 keys are supported")
+}
+
+// ringConfig adalah format file key ring
+type ringConfig struct {
+	Active string `json:"active"`
+	Keys   []struct {
+		ID          string     `json:"kid"`
+		File        string     `json:"file"` // PEM private key, atau public key untuk key yang hanya verifikasi
+		VerifyUntil *time.Time `json:"verify_until"`
+	} `json:"keys"`
+}
+
+// LoadFile membaca key ring dari file JSON, misalnya:
+//
+//	{
+//	  "active": "2026-10",
+//	  "keys": [
+//	    {"kid": "2026-10", "file": "keys/2026-10.pem"},
+//	    {"kid": "2026-07", "file": "keys/2026-07.pem", "verify_until": "2026-10-19T00:00:00Z"}
+//	  ]
+//	}
+//
+// Path file key relatif terhadap lokasi file key ring. Untuk merotasi key,
+// tambahkan key baru, jadikan active, lalu isi verify_until key lama dengan
+// akhir masa tenggang (paling tidak selama masa berlaku access token).
+func LoadFile(path string) (*Ring, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var config ringConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("jwtkeys: invalid key ring %s: %v", path, err)
+	}
+
+	keys := make([]Key, 0, len(config.Keys))
+	for _, entry := range config.Keys {
+		file := entry.File
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(filepath.Dir(path), file)
+		}
+		key, err := loadKeyFile(file)
+		if err != nil {
+			return nil, err
+		}
+		key.ID = entry.ID
+		if entry.VerifyUntil != nil {
+			key.VerifyUntil = *entry.VerifyUntil
+		}
+		keys = append(keys, key)
+	}
+	return NewRing(config.Active, keys...)
+}
+
+func loadKeyFile(path string) (Key, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Key{}, err
+	}
+	key, err := ParsePEM(data)
+	if err != nil {
+		return Key{}, fmt.Errorf("%s: %v", path, err)
+	}
+	return key, nil
+}
+
+// LoadFromEnv membaca key ring dari file pada JWT_KEYRING_FILE, atau satu
+// private key pada JWT_PRIVATE_KEY_FILE dengan kid dari JWT_KEY_ID (bawaannya
+// JWK thumbprint). Error dikembalikan jika tidak ada key yang diatur.
+func LoadFromEnv() (*Ring, error) {
+	if path := os.Getenv("JWT_KEYRING_FILE"); path != "" {
+		return LoadFile(path)
+	}
+
+	path := os.Getenv("JWT_PRIVATE_KEY_FILE")
+	if path == "" {
+		return nil, fmt.Errorf("%w: set JWT_KEYRING_FILE or JWT_PRIVATE_KEY_FILE", ErrNoSigningKey)
+	}
+	key, err := loadKeyFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key.ID = os.Getenv("JWT_KEY_ID")
+	if key.ID == "" {
+		if key.ID, err = Thumbprint(key.PublicKey); err != nil {
+			return nil, err
+		}
+	}
+	return NewRing(key.ID, key)
+}
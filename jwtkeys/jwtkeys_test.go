@@ -0,0 +1,157 @@
+package jwtkeys
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+func newRSAKey(t *testing.T, id string) Key {
+	t.Helper()
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Key{ID: id, Algorithm: AlgRS256, PrivateKey: private, PublicKey: &private.PublicKey}
+}
+
+func newEdDSAKey(t *testing.T, id string) Key {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Key{ID: id, Algorithm: AlgEdDSA, PrivateKey: private, PublicKey: public}
+}
+
+func sign(t *testing.T, ring *Ring) string {
+	t.Helper()
+	token, err := ring.Sign(jwt.MapClaims{"userID": 1, "exp": time.Now().Add(time.Minute).Unix()})
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	return token
+}
+
+// isUnknownKey melaporkan apakah jwt.Parse gagal karena Keyfunc mengembalikan
+// ErrUnknownKey; jwt-go v3 membungkusnya tanpa Unwrap
+func isUnknownKey(err error) bool {
+	var validationErr *jwt.ValidationError
+	return errors.As(err, &validationErr) && validationErr.Inner == ErrUnknownKey
+}
+
+func TestRingSignVerify(t *testing.T) {
+	for _, key := range []Key{newRSAKey(t, "rsa"), newEdDSAKey(t, "ed")} {
+		t.Run(key.Algorithm, func(t *testing.T) {
+			ring, err := NewRing(key.ID, key)
+			if err != nil {
+				t.Fatalf("NewRing() error = %v", err)
+			}
+
+			token, err := jwt.Parse(sign(t, ring), ring.Keyfunc)
+			if err != nil || !token.Valid {
+				t.Fatalf("Parse() error = %v, valid = %v", err, token != nil && token.Valid)
+			}
+			if token.Header["kid"] != key.ID {
+				t.Errorf("kid = %v, want %q", token.Header["kid"], key.ID)
+			}
+			if token.Method.Alg() != key.Algorithm {
+				t.Errorf("alg = %q, want %q", token.Method.Alg(), key.Algorithm)
+			}
+		})
+	}
+}
+
+func TestRingKeySelection(t *testing.T) {
+	oldKey := newEdDSAKey(t, "2026-07")
+	newKey := newRSAKey(t, "2026-10")
+
+	oldRing, err := NewRing(oldKey.ID, oldKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldToken := sign(t, oldRing)
+
+	// Setelah rotasi, key lama hanya dipakai verifikasi selama masa tenggang
+	oldKey.PrivateKey = nil
+	oldKey.VerifyUntil = time.Now().Add(time.Hour)
+	ring, err := NewRing(newKey.ID, oldKey, newKey)
+	if err != nil {
+		t.Fatalf("NewRing() error = %v", err)
+	}
+
+	newToken, err := jwt.Parse(sign(t, ring), ring.Keyfunc)
+	if err != nil {
+		t.Fatalf("Parse(new token) error = %v", err)
+	}
+	if newToken.Header["kid"] != newKey.ID {
+		t.Errorf("new token kid = %v, want %q", newToken.Header["kid"], newKey.ID)
+	}
+	if _, err := jwt.Parse(oldToken, ring.Keyfunc); err != nil {
+		t.Errorf("Parse(old token) during grace period error = %v", err)
+	}
+
+	// Token dengan kid yang tidak dikenal ditolak
+	unknownRing, err := NewRing("other", newEdDSAKey(t, "other"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jwt.Parse(sign(t, unknownRing), ring.Keyfunc); !isUnknownKey(err) {
+		t.Errorf("Parse(unknown kid) error = %v, want %v", err, ErrUnknownKey)
+	}
+
+	// Token yang kid-nya ditukar ke key dengan algoritma lain ditolak
+	forged := jwt.NewWithClaims(SigningMethodEdDSA, jwt.MapClaims{"userID": 1})
+	forged.Header["kid"] = newKey.ID
+	forgedToken, err := forged.SignedString(newEdDSAKey(t, "attacker").PrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jwt.Parse(forgedToken, ring.Keyfunc); err == nil {
+		t.Error("Parse(token with mismatched algorithm) succeeded")
+	}
+}
+
+func TestRingGracePeriodExpiry(t *testing.T) {
+	oldKey := newEdDSAKey(t, "old")
+	oldRing, err := NewRing(oldKey.ID, oldKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldToken := sign(t, oldRing)
+
+	oldKey.PrivateKey = nil
+	oldKey.VerifyUntil = time.Now().Add(-time.Second)
+	newKey := newEdDSAKey(t, "new")
+	ring, err := NewRing(newKey.ID, oldKey, newKey)
+	if err != nil {
+		t.Fatalf("NewRing() error = %v", err)
+	}
+
+	if _, err := jwt.Parse(oldToken, ring.Keyfunc); !isUnknownKey(err) {
+		t.Errorf("Parse(old token) after grace period error = %v, want %v", err, ErrUnknownKey)
+	}
+	jwks := ring.JWKS()
+	if len(jwks.Keys) != 1 || jwks.Keys[0].Kid != newKey.ID {
+		t.Errorf("JWKS() = %+v, want only %q", jwks.Keys, newKey.ID)
+	}
+}
+
+func TestNewRingRejectsExpiredActiveKey(t *testing.T) {
+	key := newEdDSAKey(t, "expired")
+	key.VerifyUntil = time.Now().Add(-time.Minute)
+	if _, err := NewRing(key.ID, key); !errors.Is(err, ErrNoSigningKey) {
+		t.Errorf("NewRing() error = %v, want %v", err, ErrNoSigningKey)
+	}
+
+	verifyOnly := newEdDSAKey(t, "verify-only")
+	verifyOnly.PrivateKey = nil
+	if _, err := NewRing(verifyOnly.ID, verifyOnly); !errors.Is(err, ErrNoSigningKey) {
+		t.Errorf("NewRing(verify-only active key) error = %v, want %v", err, ErrNoSigningKey)
+	}
+}
@@ -0,0 +1,129 @@
+// Package mailer mengirim email transaksional ke pengguna, misalnya tautan
+// reset password dan verifikasi email.
+package mailer
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message adalah satu email teks
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer mengirim email
+type Mailer interface {
+	Send(Message) error
+}
+
+// format menyusun email dalam format RFC 5322
+func format(from string, msg Message, date time.Time) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(msg.Body)
+	b.WriteString("\r\n")
+	return b.Bytes()
+}
+
+// SMTPMailer mengirim email lewat server SMTP. Untuk pengujian lokal, Addr dapat
+// diarahkan ke server SMTP tiruan seperti MailHog ("localhost:1025") tanpa Username.
+type SMTPMailer struct {
+	Addr     string // host:port
+	Username string // kosong jika server tidak memerlukan autentikasi
+	Password string
+	From     string
+}
+
+// Send mengirim email lewat SMTP
+func (m SMTPMailer) Send(msg Message) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		host := m.Addr
+		if i := strings.LastIndex(host, ":"); i >= 0 {
+			host = host[:i]
+		}
+		auth = smtp.PlainAuth("", m.Username, m.Password, host)
+	}
+	return smtp.SendMail(m.Addr, auth, m.From, []string{msg.To}, format(m.From, msg, time.Now()))
+}
+
+// MemoryMailer menyimpan email yang dikirim di memori, untuk pengujian
+type MemoryMailer struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+// Send menyimpan email
+func (m *MemoryMailer) Send(msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// Messages mengembalikan salinan semua email yang sudah dikirim
+func (m *MemoryMailer) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Message(nil), m.messages...)
+}
+
+// FileMailer menulis setiap email sebagai file .eml di Dir, untuk pengembangan
+// dan pengujian tanpa server SMTP
+type FileMailer struct {
+	Dir  string
+	From string
+}
+
+// Send menulis email ke file baru
+func (m FileMailer) Send(msg Message) error {
+	if err := os.MkdirAll(m.Dir, 0o700); err != nil {
+		return err
+	}
+	now := time.Now()
+	f, err := ioutil.TempFile(m.Dir, now.Format("20060102-150405")+"-*.eml")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(format(m.From, msg, now)); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// FromEnv membuat Mailer dari env: SMTPMailer jika MAIL_SMTP_ADDR diisi,
+// selain itu FileMailer di MAIL_DIR (bawaannya "mail")
+func FromEnv() Mailer {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "no-reply@localhost"
+	}
+	if addr := os.Getenv("MAIL_SMTP_ADDR"); addr != "" {
+		return SMTPMailer{
+			Addr:     addr,
+			Username: os.Getenv("MAIL_SMTP_USERNAME"),
+			Password: os.Getenv("MAIL_SMTP_PASSWORD"),
+			From:     from,
+		}
+	}
+	dir := os.Getenv("MAIL_DIR")
+	if dir == "" {
+		dir = "mail"
+	}
+	return FileMailer{Dir: filepath.Clean(dir), From: from}
+}
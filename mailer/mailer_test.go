@@ -0,0 +1,73 @@
+package mailer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestMemoryMailer(t *testing.T) {
+	var m MemoryMailer
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := m.Send(Message{To: "user@example.com", Subject: "Verify your email"}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	messages := m.Messages()
+	if len(messages) != 10 {
+		t.Fatalf("Messages() returned %d messages, want 10", len(messages))
+	}
+
+	// Messages mengembalikan salinan, bukan slice internal
+	messages[0].To = "changed@example.com"
+	if got := m.Messages()[0].To; got != "user@example.com" {
+		t.Errorf("stored message To = %q after modifying the copy", got)
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mailer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	m := FileMailer{Dir: filepath.Join(dir, "mail"), From: "no-reply@example.com"}
+	for _, to := range []string{"a@example.com", "b@example.com"} {
+		if err := m.Send(Message{To: to, Subject: "Reset your password", Body: "Open the link"}); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+
+	files, err := filepath.Glob(filepath.Join(m.Dir, "*.eml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("wrote %d .eml files, want 2", len(files))
+	}
+
+	data, err := ioutil.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"From: no-reply@example.com\r\n",
+		"Subject: Reset your password\r\n",
+		"Content-Type: text/plain; charset=UTF-8\r\n\r\nOpen the link\r\n",
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("email %q does not contain %q", data, want)
+		}
+	}
+}
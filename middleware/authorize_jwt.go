@@ -3,46 +3,82 @@ package middleware
 import (
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
+	"github.com/gamaput/go-redeem/repository"
 	"github.com/gamaput/go-redeem/utils"
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
 )
 
-// AuthorizeJWT -> to authorize JWT Token
-func AuthorizeJWT() gin.HandlerFunc {
+// AuthorizeJWT -> to authorize JWT Token. Token hanya diterima jika sesinya
+// belum dicabut, sehingga logout dan pencabutan sesi langsung berlaku.
+func AuthorizeJWT(sessions repository.SessionRepository) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		const BearerSchema string = "Bearer "
-		authHeader := ctx.GetHeader("Authorization")
-		if authHeader == "" {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-				"error": "No Authorization header found"})
-
+		status, message := authenticateJWT(ctx, sessions)
+		switch {
+		case status == 0:
+		case message == "":
+			ctx.AbortWithStatus(status)
+		default:
+			ctx.AbortWithStatusJSON(status, gin.H{"error": message})
 		}
-		tokenString := authHeader[len(BearerSchema):]
-
-		if token, err := utils.ValidateToken(tokenString); err != nil {
+	}
+}
 
-			fmt.Println("token", tokenString, err.Error())
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-				"error": "Not Valid Token"})
+// OptionalJWT seperti AuthorizeJWT, tetapi request tanpa access token yang
+// valid tetap diteruskan tanpa userID dan sessionID, misalnya logout dengan
+// refresh token setelah access token kedaluwarsa
+func OptionalJWT(sessions repository.SessionRepository) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if status, message := authenticateJWT(ctx, sessions); status == http.StatusInternalServerError {
+			ctx.AbortWithStatusJSON(status, gin.H{"error": message})
+		}
+	}
+}
 
-		} else {
+// authenticateJWT memeriksa access token pada header Authorization dan
+// mengisi userID serta sessionID di context. Jika token tidak diterima,
+// status HTTP dan pesan error dikembalikan; status 0 berarti berhasil.
+func authenticateJWT(ctx *gin.Context, sessions repository.SessionRepository) (int, string) {
+	const BearerSchema string = "Bearer "
+	authHeader := ctx.GetHeader("Authorization")
+	if !strings.HasPrefix(authHeader, BearerSchema) {
+		return http.StatusUnauthorized, "No Authorization header found"
+	}
+	tokenString := authHeader[len(BearerSchema):]
 
-			if claims, ok := token.Claims.(jwt.MapClaims); !ok {
-				ctx.AbortWithStatus(http.StatusUnauthorized)
+	token, err := utils.ValidateToken(tokenString)
+	if err != nil || !token.Valid {
+		return http.StatusUnauthorized, "Not Valid Token"
+	}
 
-			} else {
-				if token.Valid {
-					ctx.Set("userID", claims["userID"])
-				} else {
-					ctx.AbortWithStatus(http.StatusUnauthorized)
-				}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	// Challenge token 2FA hanya bisa ditukar di /api/signin/mfa
+	if !ok || claims["mfa"] != nil {
+		return http.StatusUnauthorized, ""
+	}
 
-			}
-		}
+	userID, err := strconv.ParseUint(fmt.Sprint(claims["userID"]), 10, 64)
+	if err != nil {
+		return http.StatusUnauthorized, ""
+	}
+	sessionID, err := strconv.ParseUint(fmt.Sprint(claims["sessionID"]), 10, 64)
+	if err != nil {
+		return http.StatusUnauthorized, "Not Valid Token"
+	}
 
+	active, err := sessions.IsSessionActive(uint(sessionID), uint(userID))
+	if err != nil {
+		return http.StatusInternalServerError, "Failed to check session"
+	}
+	if !active {
+		return http.StatusUnauthorized, "Session has been revoked"
 	}
 
+	ctx.Set("userID", claims["userID"])
+	ctx.Set("sessionID", uint(sessionID))
+	return 0, ""
 }
@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gamaput/go-redeem/model"
+	"github.com/gamaput/go-redeem/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// IdempotencyKeyHeader adalah header yang dikirim client untuk menandai request yang boleh diulang
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotentReplayedHeader bernilai "true" pada respons yang diputar ulang dari request sebelumnya
+const IdempotentReplayedHeader = "Idempotent-Replayed"
+
+const maxIdempotencyKeyLength = 191
+
+// maxIdempotencyBodySize membatasi ukuran body yang dibaca untuk hash request,
+// sama dengan batas body pada RateLimiter
+const maxIdempotencyBodySize = maxIdentityBodySize
+
+// idempotencyWriter menyalin body respons agar dapat disimpan
+type idempotencyWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *idempotencyWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *idempotencyWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency menyimpan respons request yang memiliki header Idempotency-Key
+// selama ttl. Request ulang dengan key dan body yang sama mendapatkan respons
+// yang sama; key yang dipakai dengan body berbeda ditolak. Respons 5xx tidak
+// disimpan agar client dapat mencoba lagi dengan key yang sama.
+func Idempotency(repo repository.IdempotencyRepository, ttl time.Duration) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		key := ctx.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			ctx.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Idempotency-Key must be at most %d characters", maxIdempotencyKeyLength)})
+			return
+		}
+
+		body, err := ioutil.ReadAll(http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxIdempotencyBodySize))
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Request body too large"})
+			return
+		}
+		ctx.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		hash := sha256.New()
+		fmt.Fprintf(hash, "%s %s\n", ctx.Request.Method, ctx.Request.URL.RequestURI())
+		hash.Write(body)
+
+		// Key berlaku per route, dan per user jika request berasal dari user
+		// yang login. Route publik seperti /api/redeem tidak dibatasi per IP
+		// karena client mobile sering mengulang request dari IP lain setelah
+		// koneksinya putus; hash request memastikan respons hanya diputar
+		// ulang untuk body yang sama persis.
+		scope := ctx.FullPath()
+		if userID, ok := ctx.Get("userID"); ok {
+			scope += "#" + fmt.Sprint(userID)
+		}
+
+		record := model.IdempotencyKey{
+			Scope:       scope,
+			Key:         key,
+			RequestHash: hex.EncodeToString(hash.Sum(nil)),
+			ExpiresAt:   time.Now().Add(ttl),
+		}
+		existing, created, err := repo.BeginIdempotentRequest(&record)
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to process Idempotency-Key"})
+			return
+		}
+		if !created {
+			switch {
+			case existing.RequestHash != record.RequestHash:
+				ctx.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key has already been used for a different request"})
+			case !existing.Completed:
+				ctx.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "A request with this Idempotency-Key is still being processed"})
+			default:
+				ctx.Header(IdempotentReplayedHeader, "true")
+				ctx.Data(existing.StatusCode, existing.ContentType, existing.Response)
+				ctx.Abort()
+			}
+			return
+		}
+
+		// Jika handler panic, key dilepas sebelum panic diteruskan ke
+		// middleware Recovery agar key tidak tertahan dalam status diproses
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				if err := repo.ReleaseIdempotentRequest(record.ID); err != nil {
+					log.Printf("idempotency key %q: %v", key, err)
+				}
+				panic(recovered)
+			}
+		}()
+
+		writer := &idempotencyWriter{ResponseWriter: ctx.Writer}
+		ctx.Writer = writer
+		ctx.Next()
+
+		status := writer.Status()
+		if status >= http.StatusInternalServerError {
+			err = repo.ReleaseIdempotentRequest(record.ID)
+		} else {
+			err = repo.CompleteIdempotentRequest(record.ID, status, writer.Header().Get("Content-Type"), writer.body.Bytes())
+		}
+		if err != nil {
+			log.Printf("idempotency key %q: %v", key, err)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gamaput/go-redeem/model"
+	"github.com/gin-gonic/gin"
+)
+
+// memoryIdempotencyRepository adalah IdempotencyRepository di memori untuk test
+type memoryIdempotencyRepository struct {
+	mu     sync.Mutex
+	nextID uint
+	keys   map[string]*model.IdempotencyKey
+}
+
+func (r *memoryIdempotencyRepository) BeginIdempotentRequest(key *model.IdempotencyKey) (model.IdempotencyKey, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if existing, ok := r.keys[key.Scope+"|"+key.Key]; ok {
+		return *existing, false, nil
+	}
+	r.nextID++
+	key.ID = r.nextID
+	stored := *key
+	r.keys[key.Scope+"|"+key.Key] = &stored
+	return stored, true, nil
+}
+
+func (r *memoryIdempotencyRepository) CompleteIdempotentRequest(id uint, statusCode int, contentType string, response []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, key := range r.keys {
+		if key.ID == id {
+			key.Completed, key.StatusCode, key.ContentType, key.Response = true, statusCode, contentType, response
+		}
+	}
+	return nil
+}
+
+func (r *memoryIdempotencyRepository) ReleaseIdempotentRequest(id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for scopeKey, key := range r.keys {
+		if key.ID == id {
+			delete(r.keys, scopeKey)
+		}
+	}
+	return nil
+}
+
+func (r *memoryIdempotencyRepository) DeleteExpiredIdempotencyKeys(now time.Time) (int64, error) {
+	return 0, nil
+}
+
+func TestIdempotencyReplayFromAnotherIP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	calls := 0
+	router.POST("/api/redeem", Idempotency(&memoryIdempotencyRepository{keys: make(map[string]*model.IdempotencyKey)}, time.Hour), func(ctx *gin.Context) {
+		calls++
+		ctx.JSON(http.StatusOK, gin.H{"call": calls})
+	})
+
+	send := func(remoteAddr, body string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodPost, "/api/redeem", strings.NewReader(body))
+		request.RemoteAddr = remoteAddr
+		request.Header.Set(IdempotencyKeyHeader, "retry-1")
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	first := send("10.0.0.1:1234", `{"code":"ABCD"}`)
+	if first.Code != http.StatusOK {
+		t.Fatalf("first request status = %d, want %d", first.Code, http.StatusOK)
+	}
+
+	// Client mobile mengulang request dari IP lain setelah koneksinya putus
+	retry := send("10.0.0.2:5678", `{"code":"ABCD"}`)
+	if retry.Code != http.StatusOK || retry.Body.String() != first.Body.String() || retry.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Errorf("retry from another IP = %d %q (replayed %q), want the stored response", retry.Code, retry.Body.String(), retry.Header().Get(IdempotentReplayedHeader))
+	}
+	if calls != 1 {
+		t.Errorf("handler called %d times, want 1", calls)
+	}
+
+	if response := send("10.0.0.3:1234", `{"code":"WXYZ"}`); response.Code != http.StatusUnprocessableEntity {
+		t.Errorf("same key with a different body status = %d, want %d", response.Code, http.StatusUnprocessableEntity)
+	}
+
+	large := `{"code":"` + strings.Repeat("A", maxIdempotencyBodySize) + `"}`
+	if response := send("10.0.0.1:1234", large); response.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized body status = %d, want %d", response.Code, http.StatusRequestEntityTooLarge)
+	}
+}
@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gamaput/go-redeem/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// Header request partner
+const (
+	PartnerKeyHeader       = "X-Partner-Key"
+	PartnerTimestampHeader = "X-Partner-Timestamp"
+	PartnerNonceHeader     = "X-Partner-Nonce"
+	PartnerSignatureHeader = "X-Partner-Signature"
+)
+
+// PartnerKey adalah key context tempat AuthorizePartner menyimpan model.Partner yang terautentikasi
+const PartnerKey = "partner"
+
+const maxPartnerNonceLength = 64
+
+// maxPartnerBodySize membatasi ukuran body request partner yang dibaca untuk tanda tangan
+const maxPartnerBodySize = 1 << 20
+
+// PartnerStringToSign menyusun teks yang ditandatangani partner:
+// "<timestamp>\n<nonce>\n<METHOD>\n<path dan query>\n<hex SHA-256 body>"
+func PartnerStringToSign(timestamp, nonce, method, requestURI string, body []byte) string {
+	sum := sha256.Sum256(body)
+	return timestamp + "\n" + nonce + "\n" + method + "\n" + requestURI + "\n" + hex.EncodeToString(sum[:])
+}
+
+// SignPartnerRequest mengembalikan nilai header X-Partner-Signature, yaitu hex
+// HMAC-SHA256 dari PartnerStringToSign dengan secret partner
+func SignPartnerRequest(secret, timestamp, nonce, method, requestURI string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(PartnerStringToSign(timestamp, nonce, method, requestURI, body)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// AuthorizePartner mengautentikasi request partner dengan API key dan tanda
+// tangan HMAC. Timestamp harus berada dalam window dari waktu server dan
+// nonce tidak boleh dipakai ulang dalam window tersebut.
+func AuthorizePartner(repo repository.PartnerRepository, window time.Duration) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		apiKey := ctx.GetHeader(PartnerKeyHeader)
+		timestamp := ctx.GetHeader(PartnerTimestampHeader)
+		nonce := ctx.GetHeader(PartnerNonceHeader)
+		signature := ctx.GetHeader(PartnerSignatureHeader)
+		if apiKey == "" || timestamp == "" || nonce == "" || signature == "" {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Missing partner authentication headers"})
+			return
+		}
+		if len(nonce) > maxPartnerNonceLength {
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "X-Partner-Nonce is too long"})
+			return
+		}
+
+		now := time.Now()
+		unix, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid X-Partner-Timestamp"})
+			return
+		}
+		if skew := now.Sub(time.Unix(unix, 0)); skew > window || skew < -window {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Request timestamp is outside the allowed window"})
+			return
+		}
+
+		partner, err := repo.GetPartnerByAPIKey(apiKey)
+		if err != nil {
+			if errors.Is(err, repository.ErrPartnerNotFound) {
+				ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid partner credentials"})
+				return
+			}
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to authenticate partner"})
+			return
+		}
+
+		body, err := ioutil.ReadAll(http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxPartnerBodySize))
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Request body too large"})
+			return
+		}
+		ctx.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		expected := SignPartnerRequest(partner.Secret, timestamp, nonce, ctx.Request.Method, ctx.Request.URL.RequestURI(), body)
+		if !hmac.Equal([]byte(expected), []byte(signature)) {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid partner credentials"})
+			return
+		}
+
+		// Nonce dicatat setelah tanda tangan valid agar pihak lain tidak bisa menghabiskan nonce partner
+		if err := repo.UsePartnerNonce(partner.ID, nonce, now); err != nil {
+			if errors.Is(err, repository.ErrPartnerNonceReused) {
+				ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Request has already been used"})
+				return
+			}
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to authenticate partner"})
+			return
+		}
+
+		ctx.Set(PartnerKey, partner)
+		ctx.Next()
+	}
+}
@@ -0,0 +1,264 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InvalidAttemptKey adalah key context yang diisi handler (ctx.Set("invalidAttempt", true))
+// ketika request gagal karena input tebakan yang salah, misalnya kode redeem
+// yang tidak ada. RateLimit menghitungnya untuk lockout bertahap.
+const InvalidAttemptKey = "invalidAttempt"
+
+// RateLimit adalah batas token bucket: Requests token diisi ulang setiap Per,
+// dengan kapasitas maksimum Burst (bawaannya sama dengan Requests)
+type RateLimit struct {
+	Requests int
+	Per      time.Duration
+	Burst    int
+}
+
+// IsZero mengembalikan true jika batas tidak diatur
+func (l RateLimit) IsZero() bool {
+	return l.Requests <= 0 || l.Per <= 0
+}
+
+func (l RateLimit) burst() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+	return l.Requests
+}
+
+// ratePerSecond mengembalikan jumlah token yang diisi ulang per detik
+func (l RateLimit) ratePerSecond() float64 {
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+// ParseRateLimit mengurai batas dalam format "<jumlah>/<durasi>", misalnya "10/1m"
+func ParseRateLimit(value string) (RateLimit, error) {
+	parts := strings.SplitN(value, "/", 2)
+	if len(parts) != 2 {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q, expected <requests>/<duration>", value)
+	}
+	requests, err := strconv.Atoi(parts[0])
+	if err != nil || requests <= 0 {
+		return RateLimit{}, fmt.Errorf("invalid rate limit requests %q", parts[0])
+	}
+	per, err := time.ParseDuration(parts[1])
+	if err != nil || per <= 0 {
+		return RateLimit{}, fmt.Errorf("invalid rate limit duration %q", parts[1])
+	}
+	return RateLimit{Requests: requests, Per: per}, nil
+}
+
+// defaultMaxLockDuration adalah batas lama lockout jika MaxDuration tidak diatur
+const defaultMaxLockDuration = 24 * time.Hour
+
+// LockoutPolicy mengatur lockout bertahap: setelah MaxFailures percobaan
+// tidak valid dalam Window, key dikunci selama BaseDuration, lalu dua kali
+// lipat untuk setiap kegagalan berikutnya hingga MaxDuration (bawaannya
+// defaultMaxLockDuration)
+type LockoutPolicy struct {
+	MaxFailures  int
+	Window       time.Duration
+	BaseDuration time.Duration
+	MaxDuration  time.Duration
+}
+
+// IsZero mengembalikan true jika lockout tidak diatur
+func (p LockoutPolicy) IsZero() bool {
+	return p.MaxFailures <= 0 || p.BaseDuration <= 0
+}
+
+func (p LockoutPolicy) maxDuration() time.Duration {
+	if p.MaxDuration > 0 {
+		return p.MaxDuration
+	}
+	return defaultMaxLockDuration
+}
+
+// lockDuration mengembalikan lama lockout setelah failures kegagalan.
+// Perhitungan dilakukan dalam float64 agar kegagalan yang sangat banyak tidak
+// membuat durasinya overflow.
+func (p LockoutPolicy) lockDuration(failures int) time.Duration {
+	if failures < p.MaxFailures {
+		return 0
+	}
+	d := float64(p.BaseDuration) * math.Pow(2, float64(failures-p.MaxFailures))
+	if max := p.maxDuration(); d > float64(max) {
+		return max
+	}
+	return time.Duration(d)
+}
+
+// RateLimitResult adalah hasil pengambilan token dari bucket
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	ResetAfter time.Duration // waktu hingga bucket penuh kembali
+	RetryAfter time.Duration // waktu hingga token berikutnya tersedia, 0 jika Allowed
+}
+
+// RateLimitStore menyimpan token bucket dan hitungan kegagalan per key
+type RateLimitStore interface {
+	Take(key string, limit RateLimit, now time.Time) (RateLimitResult, error)
+	LockedFor(key string, now time.Time) (time.Duration, error)
+	RecordFailure(key string, policy LockoutPolicy, now time.Time) (time.Duration, error)
+}
+
+// IdentityField adalah field JSON body yang dipakai sebagai key rate limit,
+// dengan Normalize opsional agar penulisan yang berbeda dihitung sebagai satu identitas
+type IdentityField struct {
+	Name      string
+	Normalize func(string) string
+}
+
+// RateLimitConfig mengatur RateLimit untuk satu grup route
+type RateLimitConfig struct {
+	// Name membedakan key antar grup route, misalnya "redeem"
+	Name  string
+	Store RateLimitStore
+	// Limit berlaku per IP client
+	Limit RateLimit
+	// IdentityLimit berlaku per nilai IdentityFields pada JSON body, misalnya NoKTP atau nomor telepon
+	IdentityLimit  RateLimit
+	IdentityFields []IdentityField
+	// Lockout berlaku per IP client dan per identitas
+	Lockout LockoutPolicy
+}
+
+// RateLimiter membatasi jumlah request per IP dan per identitas dengan token
+// bucket, mengunci key yang terlalu sering mengirim input tidak valid, dan
+// menulis header RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset serta
+// Retry-After
+func RateLimiter(config RateLimitConfig) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		now := time.Now()
+		keys := []string{config.Name + ":ip:" + ctx.ClientIP()}
+		identityKeys, err := identityKeys(ctx, config)
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Request body too large"})
+			return
+		}
+		keys = append(keys, identityKeys...)
+
+		if !config.Lockout.IsZero() {
+			for _, key := range keys {
+				lockedFor, err := config.Store.LockedFor(key, now)
+				if err != nil {
+					ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to check rate limit"})
+					return
+				}
+				if lockedFor > 0 {
+					ctx.Header("Retry-After", retryAfterSeconds(lockedFor))
+					ctx.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Too many invalid attempts, try again later", "error_code": "locked_out"})
+					return
+				}
+			}
+		}
+
+		results := make([]RateLimitResult, 0, len(keys))
+		if !config.Limit.IsZero() {
+			result, err := config.Store.Take(keys[0], config.Limit, now)
+			if err != nil {
+				ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to check rate limit"})
+				return
+			}
+			results = append(results, result)
+		}
+		if !config.IdentityLimit.IsZero() {
+			for _, key := range identityKeys {
+				result, err := config.Store.Take(key, config.IdentityLimit, now)
+				if err != nil {
+					ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to check rate limit"})
+					return
+				}
+				results = append(results, result)
+			}
+		}
+
+		if len(results) > 0 {
+			// Header menampilkan batas yang paling ketat
+			strictest := results[0]
+			for _, result := range results[1:] {
+				switch {
+				case !result.Allowed && (strictest.Allowed || result.RetryAfter > strictest.RetryAfter):
+					strictest = result
+				case result.Allowed && strictest.Allowed && result.Remaining < strictest.Remaining:
+					strictest = result
+				}
+			}
+
+			ctx.Header("RateLimit-Limit", strconv.Itoa(strictest.Limit))
+			ctx.Header("RateLimit-Remaining", strconv.Itoa(strictest.Remaining))
+			ctx.Header("RateLimit-Reset", retryAfterSeconds(strictest.ResetAfter))
+			if !strictest.Allowed {
+				ctx.Header("Retry-After", retryAfterSeconds(strictest.RetryAfter))
+				ctx.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests, try again later", "error_code": "rate_limited"})
+				return
+			}
+		}
+
+		ctx.Next()
+
+		if !config.Lockout.IsZero() && ctx.GetBool(InvalidAttemptKey) {
+			for _, key := range keys {
+				// Kegagalan mencatat lockout tidak mengubah respons yang sudah dikirim
+				config.Store.RecordFailure(key, config.Lockout, now)
+			}
+		}
+	}
+}
+
+// maxIdentityBodySize membatasi ukuran JSON body yang dibaca identityKeys
+const maxIdentityBodySize = 1 << 20
+
+// identityKeys membaca JSON body tanpa mengonsumsinya dan mengembalikan key
+// untuk setiap IdentityFields yang terisi. Error dikembalikan jika body lebih
+// besar dari maxIdentityBodySize.
+func identityKeys(ctx *gin.Context, config RateLimitConfig) ([]string, error) {
+	if len(config.IdentityFields) == 0 || ctx.Request.Body == nil {
+		return nil, nil
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxIdentityBodySize))
+	ctx.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, nil
+	}
+
+	var keys []string
+	for _, field := range config.IdentityFields {
+		value, ok := fields[field.Name].(string)
+		value = strings.TrimSpace(value)
+		if !ok || value == "" {
+			continue
+		}
+		if field.Normalize != nil {
+			value = field.Normalize(value)
+		}
+		keys = append(keys, config.Name+":"+field.Name+":"+value)
+	}
+	return keys, nil
+}
+
+// retryAfterSeconds membulatkan durasi ke atas dalam detik
+func retryAfterSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,276 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// bucketResult menghitung RateLimitResult dari sisa token setelah pengambilan
+func bucketResult(allowed bool, tokens float64, limit RateLimit) RateLimitResult {
+	rate := limit.ratePerSecond()
+	burst := float64(limit.burst())
+	result := RateLimitResult{
+		Allowed:    allowed,
+		Limit:      limit.burst(),
+		Remaining:  int(math.Floor(tokens)),
+		ResetAfter: time.Duration((burst - tokens) / rate * float64(time.Second)),
+	}
+	if !allowed {
+		result.RetryAfter = time.Duration((1 - tokens) / rate * float64(time.Second))
+	}
+	return result
+}
+
+type memoryBucket struct {
+	tokens  float64
+	updated time.Time
+	// full adalah waktu yang dibutuhkan bucket kosong untuk terisi penuh
+	// menurut batas yang dipakai bucket ini
+	full time.Duration
+}
+
+type memoryFailures struct {
+	count       int
+	windowEnds  time.Time
+	lockedUntil time.Time
+}
+
+// memoryStoreMaxKeys adalah jumlah key sebelum entri yang sudah tidak berpengaruh dibersihkan
+const memoryStoreMaxKeys = 10000
+
+// MemoryRateLimitStore adalah RateLimitStore di memori proses, cocok untuk satu instance
+type MemoryRateLimitStore struct {
+	mu       sync.Mutex
+	buckets  map[string]*memoryBucket
+	failures map[string]*memoryFailures
+}
+
+// NewMemoryRateLimitStore -> returns new in-memory rate limit store
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		buckets:  make(map[string]*memoryBucket),
+		failures: make(map[string]*memoryFailures),
+	}
+}
+
+// Take mengambil satu token dari bucket key
+func (s *MemoryRateLimitStore) Take(key string, limit RateLimit, now time.Time) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	burst := float64(limit.burst())
+	bucket, ok := s.buckets[key]
+	if !ok {
+		if len(s.buckets) >= memoryStoreMaxKeys {
+			s.pruneBuckets(now)
+		}
+		bucket = &memoryBucket{tokens: burst, updated: now}
+		s.buckets[key] = bucket
+	}
+
+	bucket.tokens = math.Min(burst, bucket.tokens+now.Sub(bucket.updated).Seconds()*limit.ratePerSecond())
+	bucket.updated = now
+	bucket.full = time.Duration(burst / limit.ratePerSecond() * float64(time.Second))
+
+	allowed := bucket.tokens >= 1
+	if allowed {
+		bucket.tokens--
+	}
+	return bucketResult(allowed, bucket.tokens, limit), nil
+}
+
+// pruneBuckets menghapus bucket yang sudah terisi penuh kembali menurut batas
+// masing-masing bucket, karena grup route yang berbeda memakai batas berbeda
+func (s *MemoryRateLimitStore) pruneBuckets(now time.Time) {
+	for key, bucket := range s.buckets {
+		if now.Sub(bucket.updated) >= bucket.full {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// LockedFor mengembalikan sisa waktu lockout key
+func (s *MemoryRateLimitStore) LockedFor(key string, now time.Time) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	failures, ok := s.failures[key]
+	if !ok || !now.Before(failures.lockedUntil) {
+		return 0, nil
+	}
+	return failures.lockedUntil.Sub(now), nil
+}
+
+// RecordFailure mencatat satu percobaan tidak valid dan mengembalikan lama lockout yang berlaku
+func (s *MemoryRateLimitStore) RecordFailure(key string, policy LockoutPolicy, now time.Time) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	failures, ok := s.failures[key]
+	if !ok || (!now.Before(failures.windowEnds) && !now.Before(failures.lockedUntil)) {
+		if len(s.failures) >= memoryStoreMaxKeys {
+			s.pruneFailures(now)
+		}
+		failures = &memoryFailures{windowEnds: now.Add(policy.Window)}
+		s.failures[key] = failures
+	}
+
+	failures.count++
+	lockFor := policy.lockDuration(failures.count)
+	if lockFor > 0 {
+		failures.lockedUntil = now.Add(lockFor)
+		// Hitungan kegagalan dipertahankan selama lockout agar lockout berikutnya lebih lama
+		if failures.windowEnds.Before(failures.lockedUntil.Add(policy.Window)) {
+			failures.windowEnds = failures.lockedUntil.Add(policy.Window)
+		}
+	}
+	return lockFor, nil
+}
+
+func (s *MemoryRateLimitStore) pruneFailures(now time.Time) {
+	for key, failures := range s.failures {
+		if !now.Before(failures.windowEnds) && !now.Before(failures.lockedUntil) {
+			delete(s.failures, key)
+		}
+	}
+}
+
+// RedisEvaler adalah bagian dari client Redis yang dibutuhkan RedisRateLimitStore.
+// Client seperti go-redis dapat dibungkus dengan fungsi yang memanggil
+// client.Eval(ctx, script, keys, args...).Result().
+type RedisEvaler interface {
+	Eval(script string, keys []string, args ...interface{}) (interface{}, error)
+}
+
+// RedisEvalFunc mengubah fungsi biasa menjadi RedisEvaler
+type RedisEvalFunc func(script string, keys []string, args ...interface{}) (interface{}, error)
+
+// Eval memanggil f
+func (f RedisEvalFunc) Eval(script string, keys []string, args ...interface{}) (interface{}, error) {
+	return f(script, keys, args...)
+}
+
+// redisTakeScript mengambil satu token; sisa token dikembalikan sebagai
+// string karena Redis membulatkan angka Lua menjadi integer
+const redisTakeScript = `
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local tokens = tonumber(bucket[1]) or burst
+local ts = tonumber(bucket[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate)
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call('HMSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], ARGV[4])
+return {allowed, tostring(tokens)}
+`
+
+// redisFailureScript menambah hitungan kegagalan dan memasang lockout bila perlu
+const redisFailureScript = `
+local count = redis.call('INCR', KEYS[1])
+redis.call('PEXPIRE', KEYS[1], ARGV[1])
+local maxFailures = tonumber(ARGV[2])
+if count < maxFailures then
+	return 0
+end
+local lockFor = tonumber(ARGV[3]) * 2 ^ (count - maxFailures)
+local maxLock = tonumber(ARGV[4])
+if lockFor > maxLock then
+	lockFor = maxLock
+end
+lockFor = math.floor(lockFor)
+redis.call('SET', KEYS[2], 1, 'PX', lockFor)
+redis.call('PEXPIRE', KEYS[1], lockFor + tonumber(ARGV[1]))
+return lockFor
+`
+
+const redisLockedScript = `return redis.call('PTTL', KEYS[1])`
+
+// RedisRateLimitStore adalah RateLimitStore yang disimpan di Redis sehingga
+// batas berlaku untuk semua instance aplikasi
+type RedisRateLimitStore struct {
+	client RedisEvaler
+	prefix string
+}
+
+// NewRedisRateLimitStore -> returns new Redis-backed rate limit store; prefix
+// ditambahkan di depan setiap key Redis
+func NewRedisRateLimitStore(client RedisEvaler, prefix string) *RedisRateLimitStore {
+	return &RedisRateLimitStore{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+// Take mengambil satu token dari bucket key
+func (s *RedisRateLimitStore) Take(key string, limit RateLimit, now time.Time) (RateLimitResult, error) {
+	ratePerMs := limit.ratePerSecond() / 1000
+	ttl := int64(float64(limit.burst())/ratePerMs) + 1000
+	reply, err := s.client.Eval(redisTakeScript, []string{s.prefix + "bucket:" + key},
+		strconv.FormatFloat(ratePerMs, 'f', -1, 64), limit.burst(), now.UnixNano()/int64(time.Millisecond), ttl)
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+
+	values, ok := reply.([]interface{})
+	if !ok || len(values) != 2 {
+		return RateLimitResult{}, fmt.Errorf("unexpected redis reply %v", reply)
+	}
+	allowed, err := redisInt(values[0])
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	tokens, err := strconv.ParseFloat(fmt.Sprint(values[1]), 64)
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	return bucketResult(allowed == 1, tokens, limit), nil
+}
+
+// LockedFor mengembalikan sisa waktu lockout key
+func (s *RedisRateLimitStore) LockedFor(key string, now time.Time) (time.Duration, error) {
+	reply, err := s.client.Eval(redisLockedScript, []string{s.prefix + "lock:" + key})
+	if err != nil {
+		return 0, err
+	}
+	ms, err := redisInt(reply)
+	if err != nil || ms <= 0 {
+		return 0, err
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+// RecordFailure mencatat satu percobaan tidak valid dan mengembalikan lama lockout yang berlaku
+func (s *RedisRateLimitStore) RecordFailure(key string, policy LockoutPolicy, now time.Time) (time.Duration, error) {
+	reply, err := s.client.Eval(redisFailureScript, []string{s.prefix + "failures:" + key, s.prefix + "lock:" + key},
+		policy.Window.Milliseconds(), policy.MaxFailures, policy.BaseDuration.Milliseconds(), policy.maxDuration().Milliseconds())
+	if err != nil {
+		return 0, err
+	}
+	ms, err := redisInt(reply)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+func redisInt(value interface{}) (int64, error) {
+	switch v := value.(type) {
+	case int64:
+		return v, nil
+	case int:
+		return int64(v), nil
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	}
+	return 0, errors.New("unexpected redis reply type")
+}
@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMemoryStoreTakeRefill(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	limit := RateLimit{Requests: 2, Per: time.Second}
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		result, err := store.Take("key", limit, now)
+		if err != nil || !result.Allowed {
+			t.Fatalf("Take() #%d = %+v, %v, want allowed", i+1, result, err)
+		}
+	}
+	result, _ := store.Take("key", limit, now)
+	if result.Allowed || result.RetryAfter != 500*time.Millisecond {
+		t.Fatalf("Take() on empty bucket = %+v, want denied with RetryAfter 500ms", result)
+	}
+
+	// Satu token terisi kembali setiap 500ms
+	result, _ = store.Take("key", limit, now.Add(500*time.Millisecond))
+	if !result.Allowed || result.Remaining != 0 {
+		t.Fatalf("Take() after 500ms = %+v, want allowed with 0 remaining", result)
+	}
+
+	// Bucket tidak terisi melebihi kapasitasnya
+	result, _ = store.Take("key", limit, now.Add(time.Hour))
+	if !result.Allowed || result.Remaining != 1 || result.ResetAfter != 500*time.Millisecond {
+		t.Errorf("Take() after an hour = %+v, want allowed with 1 remaining and ResetAfter 500ms", result)
+	}
+
+	// Key lain memiliki bucket sendiri
+	if result, _ := store.Take("other", limit, now); !result.Allowed {
+		t.Errorf("Take(other key) = %+v, want allowed", result)
+	}
+}
+
+func TestRateLimiterHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/redeem", RateLimiter(RateLimitConfig{
+		Name:           "redeem",
+		Store:          NewMemoryRateLimitStore(),
+		Limit:          RateLimit{Requests: 3, Per: time.Minute},
+		IdentityLimit:  RateLimit{Requests: 1, Per: time.Minute},
+		IdentityFields: []IdentityField{{Name: "no_ktp"}},
+	}), func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+
+	send := func(noKTP string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodPost, "/redeem", strings.NewReader(`{"no_ktp":"`+noKTP+`"}`))
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	response := send("3174011205900001")
+	if response.Code != http.StatusOK {
+		t.Fatalf("first request status = %d, want %d", response.Code, http.StatusOK)
+	}
+	// Header menampilkan batas per identitas karena sisa tokennya paling sedikit
+	for header, want := range map[string]string{"RateLimit-Limit": "1", "RateLimit-Remaining": "0", "RateLimit-Reset": "60"} {
+		if got := response.Header().Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+
+	response = send("3174011205900001")
+	if response.Code != http.StatusTooManyRequests {
+		t.Fatalf("second request for the same identity status = %d, want %d", response.Code, http.StatusTooManyRequests)
+	}
+	if got := response.Header().Get("Retry-After"); got != "60" {
+		t.Errorf("Retry-After = %q, want %q", got, "60")
+	}
+
+	// Identitas lain masih boleh sampai batas per IP habis; request yang
+	// ditolak tetap memakai token IP
+	if response = send("3201015205900002"); response.Code != http.StatusOK {
+		t.Fatalf("request for another identity status = %d, want %d", response.Code, http.StatusOK)
+	}
+	response = send("3273017101050003")
+	if response.Code != http.StatusTooManyRequests || response.Header().Get("Retry-After") != "20" {
+		t.Errorf("request over the IP limit = %d with Retry-After %q, want %d with 20", response.Code, response.Header().Get("Retry-After"), http.StatusTooManyRequests)
+	}
+}
+
+func TestMemoryStoreLockoutEscalation(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	policy := LockoutPolicy{MaxFailures: 3, Window: time.Minute, BaseDuration: time.Second, MaxDuration: 4 * time.Second}
+	now := time.Now()
+
+	for i, want := range []time.Duration{0, 0, time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second} {
+		lockFor, err := store.RecordFailure("key", policy, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lockFor != want {
+			t.Errorf("RecordFailure() #%d = %v, want %v", i+1, lockFor, want)
+		}
+	}
+	if lockedFor, _ := store.LockedFor("key", now.Add(time.Second)); lockedFor != 3*time.Second {
+		t.Errorf("LockedFor() = %v, want 3s", lockedFor)
+	}
+	if lockedFor, _ := store.LockedFor("key", now.Add(4*time.Second)); lockedFor != 0 {
+		t.Errorf("LockedFor() after the lockout = %v, want 0", lockedFor)
+	}
+
+	// Kegagalan setelah lockout berakhir, masih dalam window, menaikkan lockout lagi
+	if lockFor, _ := store.RecordFailure("key", policy, now.Add(5*time.Second)); lockFor != 4*time.Second {
+		t.Errorf("RecordFailure() after the lockout = %v, want 4s", lockFor)
+	}
+	// Setelah window berakhir hitungan dimulai dari awal
+	if lockFor, _ := store.RecordFailure("key", policy, now.Add(time.Hour)); lockFor != 0 {
+		t.Errorf("RecordFailure() after the window = %v, want 0", lockFor)
+	}
+}
+
+func TestLockDurationDefaultCap(t *testing.T) {
+	policy := LockoutPolicy{MaxFailures: 1, Window: time.Minute, BaseDuration: time.Minute}
+	for _, failures := range []int{20, 64, 10000} {
+		if got := policy.lockDuration(failures); got != defaultMaxLockDuration {
+			t.Errorf("lockDuration(%d) = %v, want %v", failures, got, defaultMaxLockDuration)
+		}
+	}
+
+	// Store Redis menerima batas yang sama
+	var maxLock interface{}
+	store := NewRedisRateLimitStore(RedisEvalFunc(func(script string, keys []string, args ...interface{}) (interface{}, error) {
+		maxLock = args[3]
+		return int64(0), nil
+	}), "test:")
+	if _, err := store.RecordFailure("key", policy, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if maxLock != defaultMaxLockDuration.Milliseconds() {
+		t.Errorf("redis max lock argument = %v, want %d", maxLock, defaultMaxLockDuration.Milliseconds())
+	}
+}
@@ -0,0 +1,55 @@
+package model
+
+import (
+	"time"
+
+	"github.com/gamaput/go-redeem/codeformat"
+	"gorm.io/gorm"
+)
+
+// Status kampanye
+const (
+	CampaignStatusDraft  = "draft"
+	CampaignStatusActive = "active"
+	CampaignStatusPaused = "paused"
+	CampaignStatusEnded  = "ended"
+)
+
+// Campaign adalah model untuk promosi yang memiliki kode redeem dan hadiahnya sendiri
+type Campaign struct {
+	gorm.Model
+	Name    string    `json:"name"`
+	StartAt time.Time `json:"start_at"`
+	EndAt   time.Time `json:"end_at"`
+	Status  string    `json:"status" gorm:"default:draft"`
+	// NoPrizeWeight adalah bobot hasil "tidak dapat hadiah / coba lagi" saat pengundian
+	NoPrizeWeight uint `json:"no_prize_weight"`
+	// CodeFormat adalah format kode bawaan untuk kampanye ini
+	CodeFormat codeformat.Spec `json:"code_format" gorm:"embedded;embeddedPrefix:code_"`
+}
+
+// TableName mengembalikan nama tabel untuk model Campaign
+func (Campaign) TableName() string {
+	return "campaigns"
+}
+
+// IsValidCampaignStatus memeriksa apakah status termasuk status kampanye yang dikenal
+func IsValidCampaignStatus(status string) bool {
+	switch status {
+	case CampaignStatusDraft, CampaignStatusActive, CampaignStatusPaused, CampaignStatusEnded:
+		return true
+	}
+	return false
+}
+
+// CodeSpec mengembalikan format kode kampanye; kampanye lama yang belum
+// menyimpan format memakai codeformat.Legacy
+func (c Campaign) CodeSpec() codeformat.Spec {
+	return c.CodeFormat.OrDefault(codeformat.Legacy)
+}
+
+// IsActive mengembalikan true jika kampanye berstatus active dan now berada
+// di antara StartAt dan EndAt
+func (c Campaign) IsActive(now time.Time) bool {
+	return c.Status == CampaignStatusActive && !now.Before(c.StartAt) && now.Before(c.EndAt)
+}
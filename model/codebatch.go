@@ -0,0 +1,30 @@
+package model
+
+import (
+	"time"
+
+	"github.com/gamaput/go-redeem/codeformat"
+	"gorm.io/gorm"
+)
+
+// CodeBatch mencatat satu kali pembuatan kode redeem secara massal
+type CodeBatch struct {
+	gorm.Model
+	CampaignID uint   `json:"campaign_id" gorm:"index"`
+	Label      string `json:"label"`
+	Count      int    `json:"count"`
+	CreatedBy  uint   `json:"created_by"` // ID user yang membuat batch
+	// ValidFrom dan ValidUntil adalah masa berlaku yang diwariskan ke setiap kode dalam batch
+	ValidFrom  *time.Time `json:"valid_from"`
+	ValidUntil *time.Time `json:"valid_until"`
+	// MaxUses dan PerIdentityLimit diwariskan ke setiap kode dalam batch
+	MaxUses          int `json:"max_uses" gorm:"default:1"`
+	PerIdentityLimit int `json:"per_identity_limit" gorm:"default:1"`
+	// CodeFormat adalah format yang dipakai untuk kode dalam batch ini
+	CodeFormat codeformat.Spec `json:"code_format" gorm:"embedded;embeddedPrefix:code_"`
+}
+
+// TableName mengembalikan nama tabel untuk model CodeBatch
+func (CodeBatch) TableName() string {
+	return "code_batches"
+}
@@ -0,0 +1,26 @@
+package model
+
+import "gorm.io/gorm"
+
+// Aksi pada riwayat pencabutan kode
+const (
+	RevocationActionRevoke   = "revoke"
+	RevocationActionUnrevoke = "unrevoke"
+)
+
+// CodeRevocation mencatat setiap pencabutan dan pembatalan pencabutan,
+// baik untuk satu kode maupun untuk satu batch
+type CodeRevocation struct {
+	gorm.Model
+	RedeemCodeID uint   `json:"redeem_code_id" gorm:"index"` // 0 jika aksi berlaku untuk satu batch
+	BatchID      uint   `json:"batch_id" gorm:"index"`       // 0 jika aksi berlaku untuk satu kode
+	Action       string `json:"action"`
+	Reason       string `json:"reason"`
+	ActorID      uint   `json:"actor_id"` // ID user yang melakukan aksi
+	Affected     int64  `json:"affected"` // jumlah kode yang berubah status
+}
+
+// TableName mengembalikan nama tabel untuk model CodeRevocation
+func (CodeRevocation) TableName() string {
+	return "code_revocations"
+}
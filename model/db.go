@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"time"
 
+	"github.com/gamaput/go-redeem/phone"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -37,10 +39,329 @@ func DBConnection() (*gorm.DB, error) {
 		return nil, err
 	}
 
-	err = db.AutoMigrate(&User{}, &Product{}, &RedeemCode{}, &Prize{})
+	if err := prepareRedeemCodeIndex(db); err != nil {
+		return nil, err
+	}
+
+	// Diperiksa sebelum AutoMigrate menambahkan kolom atau tabelnya
+	backfillFulfillment := !db.Migrator().HasColumn(&Redemption{}, "fulfillment_status")
+	seedRoleGrants := !db.Migrator().HasTable(&RoleGrant{})
+
+	err = db.AutoMigrate(&User{}, &Product{}, &Campaign{}, &CodeBatch{}, &RedeemCode{}, &Participant{}, &Redemption{}, &CodeRevocation{}, &FulfillmentTransition{}, &Prize{}, &InventoryEntry{}, &IdempotencyKey{}, &WebhookSubscription{}, &OutboxEvent{}, &WebhookDelivery{}, &WebhookDeliveryAttempt{}, &Partner{}, &PartnerIssuance{}, &PartnerNonce{}, &Session{}, &RefreshToken{}, &UserToken{}, &UserTOTP{}, &RecoveryCode{}, &UsedMFAChallenge{}, &LoginThrottle{}, &LoginLockoutEvent{}, &RoleGrant{})
 	if err != nil {
 		return nil, err
 	}
 
+	// Tabel redeem_codes yang baru dibuat AutoMigrate memakai collation bawaan
+	if err := useBinaryCodeCollation(db); err != nil {
+		return nil, err
+	}
+
+	if err := migrateLegacyCampaign(db); err != nil {
+		return nil, err
+	}
+
+	if err := migrateRedeemCodeStatus(db); err != nil {
+		return nil, err
+	}
+
+	if err := migrateLegacyRedemptions(db); err != nil {
+		return nil, err
+	}
+
+	if err := migrateOpeningInventory(db); err != nil {
+		return nil, err
+	}
+
+	if backfillFulfillment {
+		if err := migrateFulfillmentStatus(db); err != nil {
+			return nil, err
+		}
+	}
+
+	if seedRoleGrants {
+		if err := migrateRoleGrants(db); err != nil {
+			return nil, err
+		}
+	}
+
 	return db, nil
 }
+
+// prepareRedeemCodeIndex menyiapkan kolom redeem_codes.code sebelum
+// AutoMigrate menambahkan unique index. Kode lama memakai alfabet yang
+// membedakan huruf besar dan kecil, sehingga di MySQL kolomnya diubah ke
+// collation biner lebih dulu; tanpa itu kode yang hanya berbeda huruf
+// besar/kecil dianggap sama dan pembuatan index gagal. Kode yang benar-benar
+// sama lalu dirapikan dengan removeDuplicateCodes.
+func prepareRedeemCodeIndex(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&RedeemCode{}) || db.Migrator().HasIndex(&RedeemCode{}, "Code") {
+		return nil
+	}
+	if err := useBinaryCodeCollation(db); err != nil {
+		return err
+	}
+	return removeDuplicateCodes(db)
+}
+
+// useBinaryCodeCollation mengubah kolom redeem_codes.code di MySQL ke
+// utf8mb4_bin agar pencarian dan unique index kode membedakan huruf besar
+// dan kecil. Normalisasi huruf untuk format baru sudah dilakukan codeformat.
+func useBinaryCodeCollation(db *gorm.DB) error {
+	if db.Dialector.Name() != "mysql" {
+		return nil
+	}
+	var collation string
+	if err := db.Raw("SELECT COALESCE(COLLATION_NAME, '') FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME = ?", "redeem_codes", "code").
+		Scan(&collation).Error; err != nil {
+		return err
+	}
+	if collation == "utf8mb4_bin" {
+		return nil
+	}
+	return db.Exec("ALTER TABLE redeem_codes MODIFY code varchar(32) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin").Error
+}
+
+// removeDuplicateCodes merapikan kode yang tersimpan lebih dari sekali. Baris
+// yang selama ini ditemukan saat penukaran (ID terkecil yang belum dihapus)
+// dipertahankan; baris lain tidak pernah bisa ditukarkan, sehingga kodenya
+// diberi akhiran "~dup<ID>" yang tidak mungkin diketik peserta. Setiap baris
+// yang diubah dicatat di log.
+func removeDuplicateCodes(db *gorm.DB) error {
+	var duplicates []string
+	if err := db.Unscoped().Model(&RedeemCode{}).Group("code").Having("COUNT(*) > 1").Pluck("code", &duplicates).Error; err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, code := range duplicates {
+			var rows []RedeemCode
+			if err := tx.Unscoped().Where("code = ?", code).Order("id").Find(&rows).Error; err != nil {
+				return err
+			}
+			keep := rows[0].ID
+			for _, row := range rows {
+				if !row.DeletedAt.Valid {
+					keep = row.ID
+					break
+				}
+			}
+
+			for _, row := range rows {
+				if row.ID == keep {
+					continue
+				}
+				renamed := fmt.Sprintf("%.20s~dup%d", code, row.ID)
+				if err := tx.Unscoped().Model(&RedeemCode{}).Where("id = ?", row.ID).Update("code", renamed).Error; err != nil {
+					return err
+				}
+				log.Printf("[Migrate] redeem code %d duplicates code %q of redeem code %d, renamed to %q", row.ID, code, keep, renamed)
+			}
+		}
+		return nil
+	})
+}
+
+// LegacyCampaignName adalah nama kampanye yang menampung kode redeem dan
+// hadiah yang dibuat sebelum ada kampanye
+const LegacyCampaignName = "Legacy"
+
+// migrateLegacyCampaign memindahkan kode redeem dan hadiah tanpa kampanye
+// (campaign_id 0) ke kampanye LegacyCampaignName yang aktif tanpa batas waktu,
+// sehingga kode lama tetap bisa ditukarkan dan hadiah lama tetap diundi.
+// Kampanye hanya dibuat jika ada data tanpa kampanye, dan migrasi aman diulang.
+func migrateLegacyCampaign(db *gorm.DB) error {
+	var orphanCodes, orphanPrizes int64
+	if err := db.Unscoped().Model(&RedeemCode{}).Where("campaign_id = 0").Count(&orphanCodes).Error; err != nil {
+		return err
+	}
+	if err := db.Unscoped().Model(&Prize{}).Where("campaign_id = 0").Count(&orphanPrizes).Error; err != nil {
+		return err
+	}
+	if orphanCodes == 0 && orphanPrizes == 0 {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		campaign := Campaign{
+			Name:    LegacyCampaignName,
+			StartAt: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+			EndAt:   time.Date(2999, 12, 31, 0, 0, 0, 0, time.UTC),
+			Status:  CampaignStatusActive,
+		}
+		if err := tx.Where(Campaign{Name: LegacyCampaignName}).FirstOrCreate(&campaign).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&RedeemCode{}).Where("campaign_id = 0").Update("campaign_id", campaign.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&Prize{}).Where("campaign_id = 0").Update("campaign_id", campaign.ID).Error; err != nil {
+			return err
+		}
+		log.Printf("[Migrate] moved %d redeem codes and %d prizes without a campaign to campaign %q (ID %d)", orphanCodes, orphanPrizes, campaign.Name, campaign.ID)
+		return nil
+	})
+}
+
+// migrateRedeemCodeStatus memindahkan kolom lama is_redeemed ke kolom status
+func migrateRedeemCodeStatus(db *gorm.DB) error {
+	if !db.Migrator().HasColumn(&RedeemCode{}, "is_redeemed") {
+		return nil
+	}
+	if err := db.Unscoped().Model(&RedeemCode{}).Where("is_redeemed = ?", true).Update("status", CodeStatusRedeemed).Error; err != nil {
+		return err
+	}
+	return db.Migrator().DropColumn(&RedeemCode{}, "is_redeemed")
+}
+
+// migrateFulfillmentStatus mengisi status pemenuhan redemption berhadiah yang
+// dibuat sebelum ada kolom fulfillment_status; hanya dijalankan sekali saat
+// kolom tersebut baru ditambahkan
+func migrateFulfillmentStatus(db *gorm.DB) error {
+	return db.Model(&Redemption{}).Where("prize_id <> 0 AND fulfillment_status = ''").Update("fulfillment_status", FulfillmentPendingVerification).Error
+}
+
+// migrateRoleGrants mencatat role setiap user yang sudah ada sebagai riwayat
+// role pertamanya dengan alasan RoleGrantReasonMigration. Role tidak diubah;
+// admin dapat meninjaunya lewat audit role. Hanya dijalankan sekali saat
+// tabel role_grants baru dibuat.
+func migrateRoleGrants(db *gorm.DB) error {
+	var users []User
+	if err := db.Where("role <> ''").Find(&users).Error; err != nil {
+		return err
+	}
+	grants := make([]RoleGrant, 0, len(users))
+	for _, user := range users {
+		grants = append(grants, RoleGrant{UserID: user.ID, Role: user.Role, Reason: RoleGrantReasonMigration})
+	}
+	if len(grants) == 0 {
+		return nil
+	}
+	return db.CreateInBatches(grants, 500).Error
+}
+
+// legacyRedeemCodeColumns adalah kolom data pemenang yang dulu disimpan
+// langsung di redeem_codes; no_ktp dihapus paling akhir karena menjadi penanda
+// bahwa migrasi belum selesai
+var legacyRedeemCodeColumns = []string{"name", "city", "address", "phone_no", "prize_id", "no_ktp"}
+
+// migrateLegacyRedemptions memindahkan data pemenang lama dari kolom
+// redeem_codes ke tabel participants dan redemptions, lalu menghapus kolomnya.
+// Kode yang sudah memiliki redemption dilewati, sehingga migrasi aman diulang.
+func migrateLegacyRedemptions(db *gorm.DB) error {
+	if !db.Migrator().HasColumn(&RedeemCode{}, "no_ktp") {
+		return nil
+	}
+
+	var columns []string
+	for _, column := range legacyRedeemCodeColumns {
+		if db.Migrator().HasColumn(&RedeemCode{}, column) {
+			columns = append(columns, column)
+		}
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var legacy []struct {
+			ID      uint
+			Name    string
+			NoKTP   string
+			City    string
+			Address string
+			PhoneNo string
+			PrizeID uint
+		}
+		if err := tx.Unscoped().Model(&RedeemCode{}).Select("id, "+strings.Join(columns, ", ")).
+			Where("status = ? AND no_ktp <> ''", CodeStatusRedeemed).
+			Where("NOT EXISTS (SELECT 1 FROM redemptions WHERE redemptions.redeem_code_id = redeem_codes.id)").
+			Scan(&legacy).Error; err != nil {
+			return err
+		}
+
+		for _, row := range legacy {
+			// Nomor lama dinormalkan ke E.164; nomor yang tidak valid
+			// disimpan apa adanya tanpa operator agar datanya tidak hilang
+			phoneNo := phone.Number{E164: row.PhoneNo}
+			if normalized, err := phone.Normalize(row.PhoneNo); err == nil {
+				phoneNo = normalized
+			}
+
+			participant := Participant{NIK: row.NoKTP}
+			if err := tx.Where(Participant{NIK: row.NoKTP}).Assign(Participant{
+				Name:          row.Name,
+				City:          row.City,
+				Address:       row.Address,
+				PhoneNo:       phoneNo.E164,
+				PhoneOperator: phoneNo.Operator,
+			}).FirstOrCreate(&participant).Error; err != nil {
+				return err
+			}
+
+			if err := tx.Create(&Redemption{
+				RedeemCodeID:  row.ID,
+				ParticipantID: participant.ID,
+				PrizeID:       row.PrizeID,
+				Name:          row.Name,
+				NoKTP:         row.NoKTP,
+				City:          row.City,
+				Address:       row.Address,
+				PhoneNo:       phoneNo.E164,
+				PhoneOperator: phoneNo.Operator,
+			}).Error; err != nil {
+				return err
+			}
+
+			if err := tx.Unscoped().Model(&RedeemCode{}).Where("id = ?", row.ID).Update("use_count", 1).Error; err != nil {
+				return err
+			}
+		}
+
+		// Kode lama yang sudah ditukarkan tanpa data pemenang tetap dihitung
+		// sekali pakai agar tidak bisa ditukarkan ulang sebagai kode multi-pakai
+		return tx.Unscoped().Model(&RedeemCode{}).Where("status = ? AND use_count = 0", CodeStatusRedeemed).Update("use_count", 1).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	// Kolom lama baru dihapus setelah semua data pemenang dipastikan pindah
+	var unmigrated int64
+	if err := db.Unscoped().Model(&RedeemCode{}).
+		Where("status = ? AND no_ktp <> ''", CodeStatusRedeemed).
+		Where("NOT EXISTS (SELECT 1 FROM redemptions WHERE redemptions.redeem_code_id = redeem_codes.id)").
+		Count(&unmigrated).Error; err != nil {
+		return err
+	}
+	if unmigrated > 0 {
+		return fmt.Errorf("migrate legacy redemptions: %d redeem codes not migrated, keeping legacy columns", unmigrated)
+	}
+
+	for _, column := range columns {
+		if err := db.Migrator().DropColumn(&RedeemCode{}, column); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateOpeningInventory mencatat saldo awal buku stok untuk hadiah yang
+// dibuat sebelum ada buku stok, sehingga saldo setiap hadiah dapat ditelusuri
+func migrateOpeningInventory(db *gorm.DB) error {
+	var prizes []Prize
+	if err := db.Where("quantity <> 0").
+		Where("NOT EXISTS (SELECT 1 FROM prize_inventory_entries WHERE prize_inventory_entries.prize_id = prizes.id)").
+		Find(&prizes).Error; err != nil {
+		return err
+	}
+	for _, prize := range prizes {
+		if err := db.Create(&InventoryEntry{
+			PrizeID: prize.ID,
+			Type:    InventoryAdjustment,
+			Delta:   prize.Quantity,
+			Balance: prize.Quantity,
+			Reason:  "opening balance",
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
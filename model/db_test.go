@@ -0,0 +1,171 @@
+package model
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestDB membuka database SQLite kosong dengan tabel models
+func newTestDB(t *testing.T, models ...interface{}) *gorm.DB {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "go-redeem-model-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(dir, "test.db")), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestMigrateLegacyCampaign(t *testing.T) {
+	db := newTestDB(t, &Campaign{}, &RedeemCode{}, &Prize{})
+
+	// Tanpa data lama tidak ada kampanye yang dibuat
+	if err := migrateLegacyCampaign(db); err != nil {
+		t.Fatal(err)
+	}
+	var campaigns int64
+	db.Model(&Campaign{}).Count(&campaigns)
+	if campaigns != 0 {
+		t.Fatalf("created %d campaigns without legacy data, want 0", campaigns)
+	}
+
+	current := Campaign{Name: "Ramadan", StartAt: time.Now(), EndAt: time.Now().Add(time.Hour), Status: CampaignStatusActive}
+	if err := db.Create(&current).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range []interface{}{
+		&RedeemCode{Code: "LEGACY1"},
+		&RedeemCode{Code: "LEGACY2", Status: CodeStatusRedeemed},
+		&RedeemCode{Code: "CURRENT1", CampaignID: current.ID},
+		&Prize{Name: "Legacy voucher", Quantity: 3},
+		&Prize{Name: "Current voucher", Quantity: 3, CampaignID: current.ID},
+	} {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := migrateLegacyCampaign(db); err != nil {
+			t.Fatalf("migrateLegacyCampaign() run %d error = %v", i+1, err)
+		}
+	}
+
+	var legacy []Campaign
+	if err := db.Where("name = ?", LegacyCampaignName).Find(&legacy).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(legacy) != 1 {
+		t.Fatalf("found %d legacy campaigns, want 1", len(legacy))
+	}
+	if !legacy[0].IsActive(time.Now()) {
+		t.Errorf("legacy campaign %+v is not active", legacy[0])
+	}
+
+	want := map[string]uint{"LEGACY1": legacy[0].ID, "LEGACY2": legacy[0].ID, "CURRENT1": current.ID}
+	var codes []RedeemCode
+	db.Find(&codes)
+	for _, code := range codes {
+		if code.CampaignID != want[code.Code] {
+			t.Errorf("code %s campaign_id = %d, want %d", code.Code, code.CampaignID, want[code.Code])
+		}
+	}
+	wantPrize := map[string]uint{"Legacy voucher": legacy[0].ID, "Current voucher": current.ID}
+	var prizes []Prize
+	db.Find(&prizes)
+	for _, prize := range prizes {
+		if prize.CampaignID != wantPrize[prize.Name] {
+			t.Errorf("prize %q campaign_id = %d, want %d", prize.Name, prize.CampaignID, wantPrize[prize.Name])
+		}
+	}
+}
+
+// legacyRedeemCode adalah tabel redeem_codes sebelum kolom code memiliki unique index
+type legacyRedeemCode struct {
+	gorm.Model
+	Code string
+}
+
+func (legacyRedeemCode) TableName() string {
+	return "redeem_codes"
+}
+
+func TestPrepareRedeemCodeIndex(t *testing.T) {
+	db := newTestDB(t, &legacyRedeemCode{})
+
+	rows := []legacyRedeemCode{{Code: "aB3xYz"}, {Code: "Ab3xYz"}, {Code: "aB3xYz"}, {Code: "q1W2e3"}, {Code: "aB3xYz"}}
+	for i := range rows {
+		if err := db.Create(&rows[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Baris pertama sudah dihapus, sehingga baris ketiga yang selama ini ditemukan
+	if err := db.Delete(&rows[0]).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := prepareRedeemCodeIndex(db); err != nil {
+		t.Fatalf("prepareRedeemCodeIndex() error = %v", err)
+	}
+	if err := db.AutoMigrate(&RedeemCode{}); err != nil {
+		t.Fatalf("AutoMigrate() after prepareRedeemCodeIndex error = %v", err)
+	}
+
+	want := map[uint]string{
+		rows[0].ID: "aB3xYz~dup" + fmt.Sprint(rows[0].ID),
+		rows[1].ID: "Ab3xYz",
+		rows[2].ID: "aB3xYz",
+		rows[3].ID: "q1W2e3",
+		rows[4].ID: "aB3xYz~dup" + fmt.Sprint(rows[4].ID),
+	}
+	var codes []RedeemCode
+	db.Unscoped().Find(&codes)
+	for _, code := range codes {
+		if code.Code != want[code.ID] {
+			t.Errorf("redeem code %d = %q, want %q", code.ID, code.Code, want[code.ID])
+		}
+	}
+
+	// Setelah index ada, migrasi tidak mengubah apa pun
+	if err := prepareRedeemCodeIndex(db); err != nil {
+		t.Fatalf("second prepareRedeemCodeIndex() error = %v", err)
+	}
+}
+
+func TestMigrateRoleGrants(t *testing.T) {
+	db := newTestDB(t, &User{}, &RoleGrant{})
+	users := []User{{Name: "Admin", Email: "admin@example.com", Role: "admin"}, {Name: "User", Email: "user@example.com", Role: "user"}}
+	if err := db.Create(&users).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := migrateRoleGrants(db); err != nil {
+		t.Fatalf("migrateRoleGrants() error = %v", err)
+	}
+	var grants []RoleGrant
+	db.Order("user_id").Find(&grants)
+	if len(grants) != 2 {
+		t.Fatalf("created %d role grants, want 2", len(grants))
+	}
+	for i, grant := range grants {
+		if grant.UserID != users[i].ID || grant.Role != users[i].Role || grant.Reason != RoleGrantReasonMigration {
+			t.Errorf("role grant %+v, want role %q of user %d from migration", grant, users[i].Role, users[i].ID)
+		}
+	}
+}
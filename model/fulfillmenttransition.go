@@ -0,0 +1,20 @@
+package model
+
+import "gorm.io/gorm"
+
+// FulfillmentTransition mencatat setiap perpindahan status pemenuhan hadiah pada redemption
+type FulfillmentTransition struct {
+	gorm.Model
+	RedemptionID   uint   `json:"redemption_id" gorm:"index"` // Kunci asing ke model Redemption
+	FromStatus     string `json:"from_status" gorm:"size:32"`
+	ToStatus       string `json:"to_status" gorm:"size:32"`
+	Courier        string `json:"courier" gorm:"size:64"`
+	TrackingNumber string `json:"tracking_number" gorm:"size:64"`
+	Note           string `json:"note"`
+	ActorID        uint   `json:"actor_id"` // ID user yang melakukan perpindahan
+}
+
+// TableName mengembalikan nama tabel untuk model FulfillmentTransition
+func (FulfillmentTransition) TableName() string {
+	return "fulfillment_transitions"
+}
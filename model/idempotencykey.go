@@ -0,0 +1,27 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// IdempotencyKey menyimpan respons request yang dikirim dengan header
+// Idempotency-Key agar request ulang dengan key dan body yang sama
+// mendapatkan respons yang sama tanpa diproses dua kali
+type IdempotencyKey struct {
+	gorm.Model
+	Scope       string    `json:"scope" gorm:"size:191;uniqueIndex:idx_idempotency_scope_key"` // route dan user pemilik key
+	Key         string    `json:"key" gorm:"size:191;uniqueIndex:idx_idempotency_scope_key"`
+	RequestHash string    `json:"request_hash" gorm:"size:64"` // SHA-256 dari method, path dan body request
+	Completed   bool      `json:"completed"`                   // false selama request pertama masih diproses
+	StatusCode  int       `json:"status_code"`
+	ContentType string    `json:"content_type"`
+	Response    []byte    `json:"-" gorm:"type:mediumblob"`
+	ExpiresAt   time.Time `json:"expires_at" gorm:"index"`
+}
+
+// TableName mengembalikan nama tabel untuk model IdempotencyKey
+func (IdempotencyKey) TableName() string {
+	return "idempotency_keys"
+}
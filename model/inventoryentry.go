@@ -0,0 +1,55 @@
+package model
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// Jenis entri pada buku stok hadiah
+const (
+	InventoryRestock    = "restock"    // penambahan stok dari pemasok
+	InventoryAward      = "award"      // hadiah dimenangkan peserta
+	InventoryAdjustment = "adjustment" // koreksi hasil stock opname, bisa positif atau negatif
+	InventoryReturn     = "return"     // hadiah kembali ke stok, misalnya karena penukaran ditolak
+	InventoryExpiry     = "expiry"     // hadiah rusak atau kedaluwarsa
+)
+
+// InventoryEntry adalah satu baris buku stok hadiah. Entri tidak pernah diubah
+// atau dihapus; Prize.Quantity selalu sama dengan Balance entri terakhir.
+type InventoryEntry struct {
+	gorm.Model
+	PrizeID      uint   `json:"prize_id" gorm:"index"` // Kunci asing ke model Prize
+	Type         string `json:"type" gorm:"size:16;index"`
+	Delta        int    `json:"delta"`   // perubahan stok, negatif jika stok berkurang
+	Balance      int    `json:"balance"` // stok setelah entri ini
+	Reason       string `json:"reason"`
+	ActorID      uint   `json:"actor_id"`                   // ID user yang mencatat, 0 jika dicatat oleh sistem
+	RedemptionID uint   `json:"redemption_id" gorm:"index"` // diisi untuk entri award dan return dari penukaran
+}
+
+// TableName mengembalikan nama tabel untuk model InventoryEntry
+func (InventoryEntry) TableName() string {
+	return "prize_inventory_entries"
+}
+
+// ValidateInventoryDelta memeriksa bahwa arah perubahan stok sesuai dengan jenis entri
+func ValidateInventoryDelta(entryType string, delta int) error {
+	if delta == 0 {
+		return errors.New("quantity must not be 0")
+	}
+	switch entryType {
+	case InventoryRestock, InventoryReturn:
+		if delta < 0 {
+			return errors.New(entryType + " quantity must be positive")
+		}
+	case InventoryAward, InventoryExpiry:
+		if delta > 0 {
+			return errors.New(entryType + " quantity must be negative")
+		}
+	case InventoryAdjustment:
+	default:
+		return errors.New("invalid inventory entry type")
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Cakupan penghitung kegagalan sign in
+const (
+	LoginThrottleAccount = "account"
+	LoginThrottleIP      = "ip"
+)
+
+// Jenis LoginLockoutEvent
+const (
+	LoginLockoutLocked   = "locked"
+	LoginLockoutUnlocked = "unlocked"
+)
+
+// LoginThrottle menghitung kegagalan sign in per akun (email) atau per IP
+// client. Setelah terlalu banyak kegagalan, key dikunci sampai LockedUntil.
+type LoginThrottle struct {
+	gorm.Model
+	ThrottleKey   string     `json:"throttle_key" gorm:"size:191;uniqueIndex"` // "account:<email>" atau "ip:<ip>"
+	Scope         string     `json:"scope" gorm:"size:16"`
+	UserID        uint       `json:"user_id" gorm:"index"` // 0 untuk IP atau email yang tidak terdaftar
+	Failures      int        `json:"failures"`
+	LastFailureAt *time.Time `json:"last_failure_at"`
+	LockedUntil   *time.Time `json:"locked_until" gorm:"index"`
+}
+
+// TableName mengembalikan nama tabel untuk model LoginThrottle
+func (LoginThrottle) TableName() string {
+	return "login_throttles"
+}
+
+// LockedFor mengembalikan sisa waktu lockout pada now, 0 jika tidak terkunci
+func (t LoginThrottle) LockedFor(now time.Time) time.Duration {
+	if t.LockedUntil == nil || !now.Before(*t.LockedUntil) {
+		return 0
+	}
+	return t.LockedUntil.Sub(now)
+}
+
+// LoginLockoutEvent mencatat setiap lockout dan pembukaannya, agar admin
+// dapat menelusuri siapa yang terkunci dan siapa yang membukanya
+type LoginLockoutEvent struct {
+	gorm.Model
+	ThrottleKey string     `json:"throttle_key" gorm:"size:191;index"`
+	Scope       string     `json:"scope" gorm:"size:16"`
+	UserID      uint       `json:"user_id" gorm:"index"`
+	Event       string     `json:"event" gorm:"size:16"`
+	Failures    int        `json:"failures"`
+	LockedUntil *time.Time `json:"locked_until"`
+	ClientIP    string     `json:"client_ip" gorm:"size:64"`
+	ActorID     uint       `json:"actor_id"` // admin yang membuka lockout, 0 jika otomatis
+	Reason      string     `json:"reason"`
+}
+
+// TableName mengembalikan nama tabel untuk model LoginLockoutEvent
+func (LoginLockoutEvent) TableName() string {
+	return "login_lockout_events"
+}
@@ -0,0 +1,53 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UserTOTP adalah secret TOTP milik user. 2FA baru aktif setelah user
+// mengonfirmasi pendaftaran dengan kode dari aplikasi authenticator.
+type UserTOTP struct {
+	gorm.Model
+	UserID      uint       `json:"user_id" gorm:"uniqueIndex"`
+	Secret      string     `json:"-" gorm:"size:64"`
+	ConfirmedAt *time.Time `json:"confirmed_at"`
+	// LastStep adalah periode TOTP terakhir yang dipakai, agar kode yang sama tidak bisa dipakai dua kali
+	LastStep int64 `json:"-"`
+}
+
+// TableName mengembalikan nama tabel untuk model UserTOTP
+func (UserTOTP) TableName() string {
+	return "user_totps"
+}
+
+// RecoveryCode adalah kode cadangan sekali pakai untuk sign in jika perangkat
+// authenticator hilang. Hanya hash kode yang disimpan.
+type RecoveryCode struct {
+	gorm.Model
+	UserID   uint       `json:"user_id" gorm:"index"`
+	CodeHash string     `json:"-" gorm:"size:64;index"`
+	UsedAt   *time.Time `json:"used_at"`
+}
+
+// TableName mengembalikan nama tabel untuk model RecoveryCode
+func (RecoveryCode) TableName() string {
+	return "recovery_codes"
+}
+
+// UsedMFAChallenge mencatat jti challenge token 2FA yang sudah ditukar dengan
+// access token, sehingga token yang sama tidak bisa dipakai lagi. Baris dapat
+// dihapus setelah ExpiresAt karena token-nya sudah kedaluwarsa.
+type UsedMFAChallenge struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	JTI       string    `json:"jti" gorm:"size:64;uniqueIndex"`
+	UserID    uint      `json:"user_id" gorm:"index"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"index"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName mengembalikan nama tabel untuk model UsedMFAChallenge
+func (UsedMFAChallenge) TableName() string {
+	return "used_mfa_challenges"
+}
@@ -0,0 +1,21 @@
+package model
+
+import "gorm.io/gorm"
+
+// Participant adalah peserta yang menukarkan kode, diidentifikasi dengan NIK.
+// Data kontak disimpan dari penukaran terakhir peserta.
+type Participant struct {
+	gorm.Model
+	NIK     string `json:"nik" gorm:"size:32;uniqueIndex"`
+	Name    string `json:"name"`
+	City    string `json:"city"`
+	Address string `json:"address"`
+	PhoneNo string `json:"phone_no" gorm:"size:20;index"` // format E.164
+	// PhoneOperator adalah operator seluler dari PhoneNo
+	PhoneOperator string `json:"phone_operator"`
+}
+
+// TableName mengembalikan nama tabel untuk model Participant
+func (Participant) TableName() string {
+	return "participants"
+}
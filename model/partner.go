@@ -0,0 +1,60 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Partner adalah mitra ritel yang menerbitkan kode redeem dari sistem POS-nya.
+// Request partner diautentikasi dengan API key dan ditandatangani dengan Secret.
+type Partner struct {
+	gorm.Model
+	Name       string `json:"name"`
+	CampaignID uint   `json:"campaign_id" gorm:"index"` // kampanye tempat kode partner diterbitkan
+	APIKeyHash string `json:"-" gorm:"size:64;uniqueIndex"`
+	APIKeyHint string `json:"api_key_hint" gorm:"size:16"` // beberapa karakter awal API key untuk identifikasi
+	Secret     string `json:"-" gorm:"size:128"`           // kunci HMAC-SHA256 untuk tanda tangan request
+	Active     bool   `json:"active"`
+	// DailyQuota dan TotalQuota membatasi jumlah kode yang diterbitkan, 0 berarti tidak dibatasi
+	DailyQuota       int  `json:"daily_quota"`
+	TotalQuota       int  `json:"total_quota"`
+	IssuedCount      int  `json:"issued_count"`
+	MaxUses          int  `json:"max_uses" gorm:"default:1"`
+	PerIdentityLimit int  `json:"per_identity_limit" gorm:"default:1"`
+	CreatedBy        uint `json:"created_by"`
+}
+
+// TableName mengembalikan nama tabel untuk model Partner
+func (Partner) TableName() string {
+	return "partners"
+}
+
+// PartnerIssuance mencatat kode yang diterbitkan untuk satu nomor struk partner.
+// Nomor struk yang sama selalu mendapatkan kode yang sama.
+type PartnerIssuance struct {
+	gorm.Model
+	PartnerID     uint   `json:"partner_id" gorm:"uniqueIndex:idx_partner_receipt"`
+	ReceiptNumber string `json:"receipt_number" gorm:"size:64;uniqueIndex:idx_partner_receipt"`
+	RedeemCodeID  uint   `json:"redeem_code_id" gorm:"index"`
+	Code          string `json:"code" gorm:"size:32"`
+}
+
+// TableName mengembalikan nama tabel untuk model PartnerIssuance
+func (PartnerIssuance) TableName() string {
+	return "partner_issuances"
+}
+
+// PartnerNonce menyimpan nonce request partner selama jendela replay agar
+// request yang sama tidak dapat dikirim ulang
+type PartnerNonce struct {
+	ID        uint      `gorm:"primarykey"`
+	PartnerID uint      `gorm:"uniqueIndex:idx_partner_nonce"`
+	Nonce     string    `gorm:"size:64;uniqueIndex:idx_partner_nonce"`
+	CreatedAt time.Time `gorm:"index"`
+}
+
+// TableName mengembalikan nama tabel untuk model PartnerNonce
+func (PartnerNonce) TableName() string {
+	return "partner_nonces"
+}
@@ -5,8 +5,16 @@ import "gorm.io/gorm"
 // Prize adalah model untuk menyimpan informasi hadiah
 type Prize struct {
 	gorm.Model
-	Name     string `json:"name"`
-	Quantity int    `json:"quantity"`
+	CampaignID uint   `json:"campaign_id" gorm:"index"` // Kunci asing ke model Campaign
+	Name       string `json:"name"`
+	// Quantity adalah saldo stok dari buku stok (InventoryEntry) dan hanya
+	// diubah bersamaan dengan pencatatan entri
+	Quantity int `json:"quantity"`
+	// Weight adalah bobot peluang hadiah ini terpilih saat pengundian
+	Weight uint `json:"weight" gorm:"default:1"`
+	// LowStockThreshold adalah batas stok untuk peringatan stok menipis, 0 jika hanya
+	// ingin diberi tahu saat stok habis
+	LowStockThreshold int `json:"low_stock_threshold"`
 }
 
 // TableName mengembalikan nama tabel untuk model Prize
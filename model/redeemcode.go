@@ -1,17 +1,37 @@
 package model
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Status kode redeem
+const (
+	CodeStatusActive   = "active"
+	CodeStatusRedeemed = "redeemed"
+	CodeStatusRevoked  = "revoked"
+	CodeStatusExpired  = "expired"
+)
 
 type RedeemCode struct {
 	gorm.Model
-	Code       string `json:"code"`
-	IsRedeemed bool   `json:"is_redeemed"`
-	PrizeID    uint   `json:"prize_id"` // Kunci asing ke model Prize
-	Name       string `json:"name"`
-	NoKTP      string `json:"no_ktp"`
-	City       string `json:"city"`
-	Address    string `json:"address"`
-	PhoneNo    string `json:"phone_no"`
+	CampaignID uint       `json:"campaign_id" gorm:"index"` // Kunci asing ke model Campaign
+	BatchID    uint       `json:"batch_id" gorm:"index"`    // Kunci asing ke model CodeBatch, 0 jika dibuat satuan
+	Code       string     `json:"code" gorm:"size:32;uniqueIndex"`
+	ValidFrom  *time.Time `json:"valid_from"`  // nil berarti berlaku sejak dibuat
+	ValidUntil *time.Time `json:"valid_until"` // nil berarti tidak kedaluwarsa
+	Status     string     `json:"status" gorm:"size:16;default:active;index"`
+	// RevokedReason, RevokedBy dan RevokedAt diisi saat kode dicabut
+	RevokedReason string     `json:"revoked_reason"`
+	RevokedBy     uint       `json:"revoked_by"`
+	RevokedAt     *time.Time `json:"revoked_at"`
+	// MaxUses adalah jumlah maksimum penukaran kode ini, PerIdentityLimit
+	// adalah jumlah maksimum penukaran per NoKTP dan UseCount adalah jumlah
+	// penukaran yang sudah terjadi
+	MaxUses          int `json:"max_uses" gorm:"default:1"`
+	PerIdentityLimit int `json:"per_identity_limit" gorm:"default:1"`
+	UseCount         int `json:"use_count"`
 }
 
 func (RedeemCode) TableName() string {
@@ -0,0 +1,90 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Status pemenuhan hadiah pada redemption
+const (
+	FulfillmentPendingVerification = "pending_verification"
+	FulfillmentApproved            = "approved"
+	FulfillmentPacked              = "packed"
+	FulfillmentShipped             = "shipped"
+	FulfillmentDelivered           = "delivered"
+	FulfillmentRejected            = "rejected"
+	FulfillmentReturned            = "returned"
+)
+
+// fulfillmentTransitions berisi perpindahan status pemenuhan yang diizinkan.
+// Hadiah yang dikembalikan kurir dapat dikemas dan dikirim ulang.
+var fulfillmentTransitions = map[string][]string{
+	FulfillmentPendingVerification: {FulfillmentApproved, FulfillmentRejected},
+	FulfillmentApproved:            {FulfillmentPacked, FulfillmentRejected},
+	FulfillmentPacked:              {FulfillmentShipped},
+	FulfillmentShipped:             {FulfillmentDelivered, FulfillmentReturned},
+	FulfillmentDelivered:           {FulfillmentReturned},
+	FulfillmentReturned:            {FulfillmentPacked},
+}
+
+// IsValidFulfillmentStatus mengembalikan true jika status adalah status pemenuhan yang dikenal
+func IsValidFulfillmentStatus(status string) bool {
+	switch status {
+	case FulfillmentPendingVerification, FulfillmentApproved, FulfillmentPacked, FulfillmentShipped,
+		FulfillmentDelivered, FulfillmentRejected, FulfillmentReturned:
+		return true
+	}
+	return false
+}
+
+// CanTransitionFulfillment mengembalikan true jika status pemenuhan boleh berpindah dari from ke to
+func CanTransitionFulfillment(from, to string) bool {
+	for _, next := range fulfillmentTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Redemption mencatat satu kali penukaran kode redeem. Data peserta disimpan
+// sesuai yang dikirim saat penukaran, terlepas dari perubahan Participant.
+type Redemption struct {
+	gorm.Model
+	RedeemCodeID  uint   `json:"redeem_code_id" gorm:"index"` // Kunci asing ke model RedeemCode
+	ParticipantID uint   `json:"participant_id" gorm:"index"` // Kunci asing ke model Participant
+	PrizeID       uint   `json:"prize_id"`                    // Kunci asing ke model Prize, 0 jika tidak dapat hadiah
+	Name          string `json:"name"`
+	NoKTP         string `json:"no_ktp" gorm:"size:32;index"`
+	City          string `json:"city"`
+	Address       string `json:"address"`
+	PhoneNo       string `json:"phone_no" gorm:"size:20;index"` // format E.164
+	PhoneOperator string `json:"phone_operator"`
+	// Data hasil penguraian NoKTP, untuk dicocokkan dengan City dan laporan per wilayah.
+	// CityMismatch menandai redemption yang City-nya tidak sesuai dengan
+	// kabupaten/kota NIK untuk ditinjau admin; redemption tetap diterima karena
+	// NIK mencatat tempat pendaftaran, bukan domisili atau alamat pengiriman.
+	ProvinceCode string     `json:"province_code" gorm:"size:2;index"`
+	Province     string     `json:"province"`
+	RegencyCode  string     `json:"regency_code" gorm:"size:4;index"`
+	Regency      string     `json:"regency"`
+	CityMismatch bool       `json:"city_mismatch" gorm:"index"`
+	IsCity       bool       `json:"is_city"`
+	DistrictCode string     `json:"district_code" gorm:"size:6"`
+	Gender       string     `json:"gender" gorm:"size:8"`
+	BirthDate    *time.Time `json:"birth_date"`
+
+	ClientIP  string `json:"client_ip" gorm:"size:64"`
+	UserAgent string `json:"user_agent"`
+
+	// FulfillmentStatus adalah status pengiriman hadiah, kosong jika tidak dapat hadiah
+	FulfillmentStatus string `json:"fulfillment_status" gorm:"size:32;index"`
+	Courier           string `json:"courier" gorm:"size:64"`
+	TrackingNumber    string `json:"tracking_number" gorm:"size:64"`
+}
+
+// TableName mengembalikan nama tabel untuk model Redemption
+func (Redemption) TableName() string {
+	return "redemptions"
+}
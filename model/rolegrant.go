@@ -0,0 +1,29 @@
+package model
+
+import "gorm.io/gorm"
+
+// Alasan pemberian role selain perubahan oleh admin lewat API
+const (
+	// RoleGrantReasonMigration menandai role yang sudah dimiliki user sebelum
+	// riwayat role dicatat, termasuk role yang mungkin dipilih sendiri lewat
+	// /api/register versi lama
+	RoleGrantReasonMigration = "migration"
+	// RoleGrantReasonBootstrap menandai admin pertama dari BOOTSTRAP_ADMIN_EMAIL
+	RoleGrantReasonBootstrap = "bootstrap"
+)
+
+// RoleGrant mencatat setiap perubahan role user dan admin yang memberikannya
+type RoleGrant struct {
+	gorm.Model
+	UserID       uint   `json:"user_id" gorm:"index"`
+	Role         string `json:"role"`
+	PreviousRole string `json:"previous_role"`
+	GrantedBy    uint   `json:"granted_by"` // 0 jika diberikan oleh sistem
+	// Reason kosong untuk perubahan oleh admin, atau salah satu RoleGrantReason
+	Reason string `json:"reason" gorm:"size:32"`
+}
+
+// TableName mengembalikan nama tabel untuk model RoleGrant
+func (RoleGrant) TableName() string {
+	return "role_grants"
+}
@@ -0,0 +1,47 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Session adalah satu sesi login user. Setiap sesi memiliki satu keluarga
+// refresh token yang dirotasi setiap kali dipakai; mencabut sesi berarti
+// mencabut semua access token dan refresh token miliknya.
+type Session struct {
+	gorm.Model
+	UserID        uint       `json:"user_id" gorm:"index"`
+	ClientIP      string     `json:"client_ip" gorm:"size:64"`
+	UserAgent     string     `json:"user_agent"`
+	LastUsedAt    time.Time  `json:"last_used_at"`
+	ExpiresAt     time.Time  `json:"expires_at"`
+	RevokedAt     *time.Time `json:"revoked_at" gorm:"index"`
+	RevokedReason string     `json:"revoked_reason,omitempty"`
+}
+
+// TableName mengembalikan nama tabel untuk model Session
+func (Session) TableName() string {
+	return "sessions"
+}
+
+// IsActive mengembalikan true jika sesi belum dicabut dan belum kedaluwarsa
+func (s Session) IsActive(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// RefreshToken menyimpan hash refresh token. Token yang sudah dirotasi
+// (UsedAt terisi) dan dipakai lagi menandakan token dicuri, sehingga seluruh
+// sesinya dicabut.
+type RefreshToken struct {
+	gorm.Model
+	SessionID uint       `json:"session_id" gorm:"index"`
+	TokenHash string     `json:"-" gorm:"size:64;uniqueIndex"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+}
+
+// TableName mengembalikan nama tabel untuk model RefreshToken
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
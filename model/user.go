@@ -1,6 +1,10 @@
 package model
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 // User merupakan model untuk data pengguna (user)
 type User struct {
@@ -9,6 +13,8 @@ type User struct {
 	Email    string `json:"email" gorm:"unique"`
 	Role     string `json:"role"`
 	Password string `json:"password"`
+	// EmailVerifiedAt diisi saat user membuka tautan verifikasi email
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
 }
 
 // TableName mengembalikan nama tabel untuk model User
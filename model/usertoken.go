@@ -0,0 +1,29 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Kegunaan UserToken
+const (
+	UserTokenPasswordReset     = "password_reset"
+	UserTokenEmailVerification = "email_verification"
+)
+
+// UserToken adalah token sekali pakai yang dikirim ke email user, misalnya
+// untuk reset password atau verifikasi email. Hanya hash token yang disimpan.
+type UserToken struct {
+	gorm.Model
+	UserID    uint       `json:"user_id" gorm:"index"`
+	Purpose   string     `json:"purpose" gorm:"size:32;index"`
+	TokenHash string     `json:"-" gorm:"size:64;uniqueIndex"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+}
+
+// TableName mengembalikan nama tabel untuk model UserToken
+func (UserToken) TableName() string {
+	return "user_tokens"
+}
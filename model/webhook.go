@@ -0,0 +1,117 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Jenis event yang dapat dilanggan lewat webhook
+const (
+	EventRedemptionCreated            = "redemption.created"
+	EventRedemptionFulfillmentUpdated = "redemption.fulfillment_updated"
+	EventPrizeStockLow                = "prize.stock_low"
+	EventCodeBatchGenerated           = "code.batch_generated"
+)
+
+// WebhookEventTypes berisi semua jenis event webhook yang dikenal
+var WebhookEventTypes = []string{
+	EventRedemptionCreated,
+	EventRedemptionFulfillmentUpdated,
+	EventPrizeStockLow,
+	EventCodeBatchGenerated,
+}
+
+// IsValidWebhookEventType mengembalikan true jika eventType adalah jenis event webhook yang dikenal
+func IsValidWebhookEventType(eventType string) bool {
+	for _, known := range WebhookEventTypes {
+		if known == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Status pengiriman webhook
+const (
+	DeliveryStatusPending   = "pending"
+	DeliveryStatusSucceeded = "succeeded"
+	DeliveryStatusDead      = "dead" // gagal setelah semua percobaan ulang
+)
+
+// WebhookSubscription adalah langganan sistem lain terhadap event webhook
+type WebhookSubscription struct {
+	gorm.Model
+	URL         string `json:"url"`
+	Secret      string `json:"-" gorm:"size:128"` // kunci HMAC-SHA256 untuk tanda tangan payload
+	EventTypes  string `json:"event_types"`       // daftar jenis event dipisah koma
+	Description string `json:"description"`
+	Active      bool   `json:"active" gorm:"index"`
+	CreatedBy   uint   `json:"created_by"`
+}
+
+// TableName mengembalikan nama tabel untuk model WebhookSubscription
+func (WebhookSubscription) TableName() string {
+	return "webhook_subscriptions"
+}
+
+// Subscribes mengembalikan true jika langganan menerima event dengan jenis eventType
+func (s WebhookSubscription) Subscribes(eventType string) bool {
+	for _, t := range strings.Split(s.EventTypes, ",") {
+		if strings.TrimSpace(t) == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// OutboxEvent adalah event yang dicatat dalam transaksi yang sama dengan
+// perubahan datanya, lalu dikirim ke pelanggan webhook oleh worker. Event
+// tidak hilang walaupun proses berhenti setelah commit.
+type OutboxEvent struct {
+	gorm.Model
+	Type        string     `json:"type" gorm:"size:64;index"`
+	Payload     string     `json:"payload" gorm:"type:mediumtext"` // data event dalam JSON
+	PublishedAt *time.Time `json:"published_at" gorm:"index"`      // waktu pengiriman dijadwalkan ke pelanggan
+}
+
+// TableName mengembalikan nama tabel untuk model OutboxEvent
+func (OutboxEvent) TableName() string {
+	return "outbox_events"
+}
+
+// WebhookDelivery adalah pengiriman satu event ke satu pelanggan webhook
+type WebhookDelivery struct {
+	gorm.Model
+	SubscriptionID uint       `json:"subscription_id" gorm:"index"`
+	EventID        uint       `json:"event_id" gorm:"index"`
+	EventType      string     `json:"event_type" gorm:"size:64"`
+	Status         string     `json:"status" gorm:"size:16;index"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at" gorm:"index"`
+	LastStatusCode int        `json:"last_status_code"`
+	LastError      string     `json:"last_error"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+
+	Log []WebhookDeliveryAttempt `json:"log,omitempty" gorm:"foreignKey:DeliveryID"`
+}
+
+// TableName mengembalikan nama tabel untuk model WebhookDelivery
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+// WebhookDeliveryAttempt mencatat satu percobaan pengiriman webhook
+type WebhookDeliveryAttempt struct {
+	gorm.Model
+	DeliveryID uint   `json:"delivery_id" gorm:"index"`
+	StatusCode int    `json:"status_code"`
+	Error      string `json:"error"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// TableName mengembalikan nama tabel untuk model WebhookDeliveryAttempt
+func (WebhookDeliveryAttempt) TableName() string {
+	return "webhook_delivery_attempts"
+}
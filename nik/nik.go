@@ -0,0 +1,183 @@
+// Package nik memvalidasi dan menguraikan Nomor Induk Kependudukan (NIK,
+// nomor KTP) Indonesia yang terdiri dari 16 digit:
+//
+//	PP RR DD TTBBYY SSSS
+//
+// PP kode provinsi, RR kode kabupaten/kota, DD kode kecamatan, TTBBYY tanggal
+// lahir (tanggal ditambah 40 untuk perempuan) dan SSSS nomor urut.
+package nik
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Jenis kelamin hasil penguraian NIK
+const (
+	GenderMale   = "male"
+	GenderFemale = "female"
+)
+
+// Length adalah jumlah digit NIK
+const Length = 16
+
+var (
+	// ErrLength dikembalikan jika NIK tidak terdiri dari 16 digit angka
+	ErrLength = errors.New("NIK must consist of exactly 16 digits")
+	// ErrProvince dikembalikan jika kode provinsi tidak dikenal
+	ErrProvince = errors.New("NIK has an unknown province code")
+	// ErrRegency dikembalikan jika kode kabupaten/kota tidak valid
+	ErrRegency = errors.New("NIK has an invalid regency/city code")
+	// ErrDistrict dikembalikan jika kode kecamatan tidak valid
+	ErrDistrict = errors.New("NIK has an invalid district code")
+	// ErrBirthDate dikembalikan jika tanggal lahir tidak valid
+	ErrBirthDate = errors.New("NIK has an invalid birth date")
+	// ErrSequence dikembalikan jika nomor urut bernilai 0000
+	ErrSequence = errors.New("NIK has an invalid sequence number")
+)
+
+// provinces memetakan kode provinsi Kemendagri ke nama provinsi
+var provinces = map[string]string{
+	"11": "Aceh",
+	"12": "Sumatera Utara",
+	"13": "Sumatera Barat",
+	"14": "Riau",
+	"15": "Jambi",
+	"16": "Sumatera Selatan",
+	"17": "Bengkulu",
+	"18": "Lampung",
+	"19": "Kepulauan Bangka Belitung",
+	"21": "Kepulauan Riau",
+	"31": "DKI Jakarta",
+	"32": "Jawa Barat",
+	"33": "Jawa Tengah",
+	"34": "DI Yogyakarta",
+	"35": "Jawa Timur",
+	"36": "Banten",
+	"51": "Bali",
+	"52": "Nusa Tenggara Barat",
+	"53": "Nusa Tenggara Timur",
+	"61": "Kalimantan Barat",
+	"62": "Kalimantan Tengah",
+	"63": "Kalimantan Selatan",
+	"64": "Kalimantan Timur",
+	"65": "Kalimantan Utara",
+	"71": "Sulawesi Utara",
+	"72": "Sulawesi Tengah",
+	"73": "Sulawesi Selatan",
+	"74": "Sulawesi Tenggara",
+	"75": "Gorontalo",
+	"76": "Sulawesi Barat",
+	"81": "Maluku",
+	"82": "Maluku Utara",
+	"91": "Papua",
+	"92": "Papua Barat",
+	"93": "Papua Selatan",
+	"94": "Papua Tengah",
+	"95": "Papua Pegunungan",
+	"96": "Papua Barat Daya",
+}
+
+// Info adalah hasil penguraian NIK
+type Info struct {
+	NIK          string    `json:"nik"`
+	ProvinceCode string    `json:"province_code"`
+	Province     string    `json:"province"`
+	RegencyCode  string    `json:"regency_code"` // 4 digit: kode provinsi dan kode kabupaten/kota
+	Regency      string    `json:"regency"`      // kosong jika kode kabupaten/kota tidak dikenal
+	IsCity       bool      `json:"is_city"`      // true untuk kota, false untuk kabupaten
+	DistrictCode string    `json:"district_code"`
+	BirthDate    time.Time `json:"birth_date"`
+	Gender       string    `json:"gender"`
+	Sequence     string    `json:"sequence"`
+}
+
+// Parse memvalidasi NIK dan menguraikan isinya. now dipakai untuk menentukan
+// abad tahun lahir dan menolak tanggal lahir di masa depan.
+func Parse(input string, now time.Time) (Info, error) {
+	nik := strings.TrimSpace(input)
+	if len(nik) != Length {
+		return Info{}, fmt.Errorf("%w: got %d characters", ErrLength, len(nik))
+	}
+	for i := 0; i < len(nik); i++ {
+		if nik[i] < '0' || nik[i] > '9' {
+			return Info{}, fmt.Errorf("%w: character %q at position %d", ErrLength, nik[i], i+1)
+		}
+	}
+
+	info := Info{
+		NIK:          nik,
+		ProvinceCode: nik[0:2],
+		RegencyCode:  nik[0:4],
+		DistrictCode: nik[0:6],
+		Sequence:     nik[12:16],
+	}
+
+	province, ok := provinces[info.ProvinceCode]
+	if !ok {
+		return Info{}, fmt.Errorf("%w %s", ErrProvince, info.ProvinceCode)
+	}
+	info.Province = province
+
+	// Kode 01-69 untuk kabupaten dan 71-99 untuk kota
+	regency, _ := strconv.Atoi(nik[2:4])
+	if regency == 0 || regency == 70 {
+		return Info{}, fmt.Errorf("%w %s", ErrRegency, nik[2:4])
+	}
+	info.IsCity = regency > 70
+	info.Regency = regencies[info.RegencyCode]
+
+	if nik[4:6] == "00" {
+		return Info{}, fmt.Errorf("%w %s", ErrDistrict, nik[4:6])
+	}
+
+	birthDate, gender, err := parseBirthDate(nik[6:12], now)
+	if err != nil {
+		return Info{}, err
+	}
+	info.BirthDate = birthDate
+	info.Gender = gender
+
+	if info.Sequence == "0000" {
+		return Info{}, fmt.Errorf("%w %s", ErrSequence, info.Sequence)
+	}
+
+	return info, nil
+}
+
+// parseBirthDate menguraikan bagian TTBBYY. Tanggal lahir perempuan ditambah 40.
+func parseBirthDate(part string, now time.Time) (time.Time, string, error) {
+	day, _ := strconv.Atoi(part[0:2])
+	month, _ := strconv.Atoi(part[2:4])
+	yy, _ := strconv.Atoi(part[4:6])
+
+	gender := GenderMale
+	if day > 40 {
+		gender = GenderFemale
+		day -= 40
+	}
+	if day < 1 || day > 31 {
+		return time.Time{}, "", fmt.Errorf("%w: day %s is out of range (01-31 for men, 41-71 for women)", ErrBirthDate, part[0:2])
+	}
+	if month < 1 || month > 12 {
+		return time.Time{}, "", fmt.Errorf("%w: month %s is out of range", ErrBirthDate, part[2:4])
+	}
+
+	// Tahun dua digit yang lebih besar dari tahun sekarang dianggap 19xx
+	year := 2000 + yy
+	if year > now.Year() {
+		year -= 100
+	}
+
+	birthDate := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if birthDate.Day() != day {
+		return time.Time{}, "", fmt.Errorf("%w: %02d-%02d-%04d does not exist", ErrBirthDate, day, month, year)
+	}
+	if birthDate.After(now) {
+		return time.Time{}, "", fmt.Errorf("%w: %02d-%02d-%04d is in the future", ErrBirthDate, day, month, year)
+	}
+	return birthDate, gender, nil
+}
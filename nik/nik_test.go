@@ -0,0 +1,95 @@
+package nik
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var now = time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		birthDate time.Time
+		gender    string
+		regency   string
+		isCity    bool
+	}{
+		{"male", "3174011205900001", time.Date(1990, 5, 12, 0, 0, 0, 0, time.UTC), GenderMale, "Jakarta Barat", true},
+		{"female day plus 40", "3201015205900002", time.Date(1990, 5, 12, 0, 0, 0, 0, time.UTC), GenderFemale, "Bogor", false},
+		{"born this century", " 3273017101050003 ", time.Date(2005, 1, 31, 0, 0, 0, 0, time.UTC), GenderFemale, "Bandung", true},
+		{"unknown regency code", "3269010101800004", time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC), GenderMale, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := Parse(tt.input, now)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.input, err)
+			}
+			if !info.BirthDate.Equal(tt.birthDate) || info.Gender != tt.gender {
+				t.Errorf("birth date, gender = %v, %q, want %v, %q", info.BirthDate, info.Gender, tt.birthDate, tt.gender)
+			}
+			if info.Regency != tt.regency || info.IsCity != tt.isCity {
+				t.Errorf("regency, is city = %q, %v, want %q, %v", info.Regency, info.IsCity, tt.regency, tt.isCity)
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  error
+	}{
+		{"too short", "317401120590001", ErrLength},
+		{"not digits", "31740112059000A1", ErrLength},
+		{"unknown province", "9974011205900001", ErrProvince},
+		{"regency 00", "3100011205900001", ErrRegency},
+		{"regency 70", "3170011205900001", ErrRegency},
+		{"district 00", "3174001205900001", ErrDistrict},
+		{"day out of range", "3174013205900001", ErrBirthDate},
+		{"female day out of range", "3174017205900001", ErrBirthDate},
+		{"month out of range", "3174011213900001", ErrBirthDate},
+		{"date does not exist", "3174013002900001", ErrBirthDate},
+		{"future birth date", "3174010112260001", ErrBirthDate},
+		{"sequence 0000", "3174011205900000", ErrSequence},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.input, now); !errors.Is(err, tt.want) {
+				t.Errorf("Parse(%q) error = %v, want %v", tt.input, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchesCity(t *testing.T) {
+	info, err := Parse("3174011205900001", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for city, want := range map[string]bool{
+		"Jakarta Barat":                    true,
+		"kota administrasi  jakarta barat": true,
+		"DKI Jakarta":                      true,
+		"Jakarta":                          true,
+		"Jakarta Selatan":                  false,
+		"Bandung":                          false,
+	} {
+		if got := info.MatchesCity(city); got != want {
+			t.Errorf("MatchesCity(%q) = %v, want %v", city, got, want)
+		}
+	}
+
+	// Kode kabupaten/kota yang tidak dikenal tidak bisa dicocokkan
+	unknown, err := Parse("3269010101800004", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !unknown.MatchesCity("Bandung") {
+		t.Error("MatchesCity() = false for an unknown regency code, want true")
+	}
+}
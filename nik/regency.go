@@ -0,0 +1,205 @@
+package nik
+
+import "strings"
+
+// regencies memetakan kode kabupaten/kota Kemendagri (4 digit) ke namanya
+// tanpa awalan "Kabupaten" atau "Kota". Kode yang tidak ada di sini tetap
+// diterima, hanya saja kotanya tidak bisa dicocokkan.
+var regencies = map[string]string{
+	// Aceh sampai Kepulauan Riau, hanya ibu kota provinsi
+	"1171": "Banda Aceh",
+	"1271": "Medan",
+	"1371": "Padang",
+	"1471": "Pekanbaru",
+	"1571": "Jambi",
+	"1671": "Palembang",
+	"1771": "Bengkulu",
+	"1871": "Bandar Lampung",
+	"1971": "Pangkal Pinang",
+	"2171": "Batam",
+	"2172": "Tanjung Pinang",
+
+	// DKI Jakarta
+	"3101": "Kepulauan Seribu",
+	"3171": "Jakarta Selatan",
+	"3172": "Jakarta Timur",
+	"3173": "Jakarta Pusat",
+	"3174": "Jakarta Barat",
+	"3175": "Jakarta Utara",
+
+	// Jawa Barat
+	"3201": "Bogor",
+	"3202": "Sukabumi",
+	"3203": "Cianjur",
+	"3204": "Bandung",
+	"3205": "Garut",
+	"3206": "Tasikmalaya",
+	"3207": "Ciamis",
+	"3208": "Kuningan",
+	"3209": "Cirebon",
+	"3210": "Majalengka",
+	"3211": "Sumedang",
+	"3212": "Indramayu",
+	"3213": "Subang",
+	"3214": "Purwakarta",
+	"3215": "Karawang",
+	"3216": "Bekasi",
+	"3217": "Bandung Barat",
+	"3218": "Pangandaran",
+	"3271": "Bogor",
+	"3272": "Sukabumi",
+	"3273": "Bandung",
+	"3274": "Cirebon",
+	"3275": "Bekasi",
+	"3276": "Depok",
+	"3277": "Cimahi",
+	"3278": "Tasikmalaya",
+	"3279": "Banjar",
+
+	// Jawa Tengah
+	"3301": "Cilacap",
+	"3302": "Banyumas",
+	"3303": "Purbalingga",
+	"3304": "Banjarnegara",
+	"3305": "Kebumen",
+	"3306": "Purworejo",
+	"3307": "Wonosobo",
+	"3308": "Magelang",
+	"3309": "Boyolali",
+	"3310": "Klaten",
+	"3311": "Sukoharjo",
+	"3312": "Wonogiri",
+	"3313": "Karanganyar",
+	"3314": "Sragen",
+	"3315": "Grobogan",
+	"3316": "Blora",
+	"3317": "Rembang",
+	"3318": "Pati",
+	"3319": "Kudus",
+	"3320": "Jepara",
+	"3321": "Demak",
+	"3322": "Semarang",
+	"3323": "Temanggung",
+	"3324": "Kendal",
+	"3325": "Batang",
+	"3326": "Pekalongan",
+	"3327": "Pemalang",
+	"3328": "Tegal",
+	"3329": "Brebes",
+	"3371": "Magelang",
+	"3372": "Surakarta",
+	"3373": "Salatiga",
+	"3374": "Semarang",
+	"3375": "Pekalongan",
+	"3376": "Tegal",
+
+	// DI Yogyakarta
+	"3401": "Kulon Progo",
+	"3402": "Bantul",
+	"3403": "Gunungkidul",
+	"3404": "Sleman",
+	"3471": "Yogyakarta",
+
+	// Jawa Timur
+	"3501": "Pacitan",
+	"3502": "Ponorogo",
+	"3503": "Trenggalek",
+	"3504": "Tulungagung",
+	"3505": "Blitar",
+	"3506": "Kediri",
+	"3507": "Malang",
+	"3508": "Lumajang",
+	"3509": "Jember",
+	"3510": "Banyuwangi",
+	"3511": "Bondowoso",
+	"3512": "Situbondo",
+	"3513": "Probolinggo",
+	"3514": "Pasuruan",
+	"3515": "Sidoarjo",
+	"3516": "Mojokerto",
+	"3517": "Jombang",
+	"3518": "Nganjuk",
+	"3519": "Madiun",
+	"3520": "Magetan",
+	"3521": "Ngawi",
+	"3522": "Bojonegoro",
+	"3523": "Tuban",
+	"3524": "Lamongan",
+	"3525": "Gresik",
+	"3526": "Bangkalan",
+	"3527": "Sampang",
+	"3528": "Pamekasan",
+	"3529": "Sumenep",
+	"3571": "Kediri",
+	"3572": "Blitar",
+	"3573": "Malang",
+	"3574": "Probolinggo",
+	"3575": "Pasuruan",
+	"3576": "Mojokerto",
+	"3577": "Madiun",
+	"3578": "Surabaya",
+	"3579": "Batu",
+
+	// Banten
+	"3601": "Pandeglang",
+	"3602": "Lebak",
+	"3603": "Tangerang",
+	"3604": "Serang",
+	"3671": "Tangerang",
+	"3672": "Cilegon",
+	"3673": "Serang",
+	"3674": "Tangerang Selatan",
+
+	// Bali
+	"5101": "Jembrana",
+	"5102": "Tabanan",
+	"5103": "Badung",
+	"5104": "Gianyar",
+	"5105": "Klungkung",
+	"5106": "Bangli",
+	"5107": "Karangasem",
+	"5108": "Buleleng",
+	"5171": "Denpasar",
+
+	// Nusa Tenggara sampai Papua, hanya ibu kota provinsi dan kota besar
+	"5271": "Mataram",
+	"5371": "Kupang",
+	"6171": "Pontianak",
+	"6271": "Palangka Raya",
+	"6371": "Banjarmasin",
+	"6471": "Balikpapan",
+	"6472": "Samarinda",
+	"6571": "Tarakan",
+	"7171": "Manado",
+	"7271": "Palu",
+	"7371": "Makassar",
+	"7471": "Kendari",
+	"7571": "Gorontalo",
+	"8171": "Ambon",
+	"8271": "Ternate",
+	"9171": "Jayapura",
+}
+
+// cityPrefixes adalah awalan yang diabaikan saat mencocokkan nama kota
+var cityPrefixes = []string{"dki ", "kabupaten ", "kab. ", "kab ", "kota administrasi ", "kota adm. ", "kota "}
+
+// MatchesCity melaporkan apakah city sesuai dengan kabupaten/kota pada NIK.
+// Awalan seperti "Kota" atau "Kab." dan huruf besar kecil diabaikan, dan nama
+// yang lebih umum diterima ("Jakarta" untuk Jakarta Selatan). Jika kode
+// kabupaten/kota tidak dikenal, city selalu dianggap sesuai. Hasilnya hanya
+// untuk menandai data yang perlu ditinjau: NIK mencatat tempat pendaftaran
+// penduduk, bukan tempat tinggal saat ini.
+func (info Info) MatchesCity(city string) bool {
+	if info.Regency == "" {
+		return true
+	}
+	regency := strings.ToLower(info.Regency)
+	input := strings.Join(strings.Fields(strings.ToLower(city)), " ")
+	for _, prefix := range cityPrefixes {
+		if strings.HasPrefix(input, prefix) {
+			input = strings.TrimPrefix(input, prefix)
+			break
+		}
+	}
+	return input == regency || strings.HasPrefix(regency, input+" ") || strings.HasPrefix(input, regency+" ")
+}
@@ -0,0 +1,118 @@
+// Package notification mengirim pemberitahuan operasional, misalnya stok
+// hadiah yang menipis, ke satu atau lebih sink (log, webhook, SMTP).
+package notification
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Jenis event yang dikirim
+const (
+	EventPrizeStockLow = "prize.stock_low"
+	EventPrizeSoldOut  = "prize.sold_out"
+)
+
+// Event adalah satu pemberitahuan. Key dipakai untuk de-duplikasi: event
+// dengan Key yang sama hanya dikirim sekali dalam jendela de-duplikasi.
+type Event struct {
+	Type       string                 `json:"type"`
+	Key        string                 `json:"key"`
+	Subject    string                 `json:"subject"`
+	Message    string                 `json:"message"`
+	Data       map[string]interface{} `json:"data,omitempty"`
+	OccurredAt time.Time              `json:"occurred_at"`
+}
+
+// Sink adalah tujuan pengiriman event
+type Sink interface {
+	Send(Event) error
+}
+
+// Notifier menerima event untuk dikirim
+type Notifier interface {
+	Notify(Event)
+}
+
+// Nop adalah Notifier yang mengabaikan semua event
+type Nop struct{}
+
+// Notify tidak melakukan apa-apa
+func (Nop) Notify(Event) {}
+
+// dispatcherQueueSize adalah jumlah event yang dapat mengantre sebelum event baru dibuang
+const dispatcherQueueSize = 256
+
+// Dispatcher mengirim event ke semua sink di goroutine terpisah, sehingga
+// pengirim event tidak menunggu sink yang lambat
+type Dispatcher struct {
+	sinks  []Sink
+	window time.Duration
+	queue  chan Event
+
+	mu   sync.Mutex
+	sent map[string]time.Time
+}
+
+// NewDispatcher -> returns new dispatcher; event dengan Key yang sama tidak
+// dikirim ulang sebelum dedupWindow berlalu
+func NewDispatcher(dedupWindow time.Duration, sinks ...Sink) *Dispatcher {
+	d := &Dispatcher{
+		sinks:  sinks,
+		window: dedupWindow,
+		queue:  make(chan Event, dispatcherQueueSize),
+		sent:   make(map[string]time.Time),
+	}
+	go d.run()
+	return d
+}
+
+// Notify mengantrekan event kecuali event dengan Key yang sama baru saja
+// dikirim. Key baru dicatat setelah event berhasil masuk antrean, sehingga
+// event yang dibuang karena antrean penuh dapat dikirim ulang.
+func (d *Dispatcher) Notify(event Event) {
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.isDuplicate(event) {
+		return
+	}
+	select {
+	case d.queue <- event:
+		if event.Key != "" && d.window > 0 {
+			d.sent[event.Key] = event.OccurredAt
+		}
+	default:
+		log.Printf("notification: queue full, dropping %s event %s", event.Type, event.Key)
+	}
+}
+
+// isDuplicate melaporkan apakah event dengan Key yang sama sudah diantrekan
+// dalam jendela de-duplikasi; d.mu harus sudah dikunci
+func (d *Dispatcher) isDuplicate(event Event) bool {
+	if event.Key == "" || d.window <= 0 {
+		return false
+	}
+
+	for key, at := range d.sent {
+		if event.OccurredAt.Sub(at) >= d.window {
+			delete(d.sent, key)
+		}
+	}
+	_, ok := d.sent[event.Key]
+	return ok
+}
+
+func (d *Dispatcher) run() {
+	for event := range d.queue {
+		for _, sink := range d.sinks {
+			if err := sink.Send(event); err != nil {
+				log.Printf("notification: failed to send %s event %s: %v", event.Type, event.Key, err)
+			}
+		}
+	}
+}
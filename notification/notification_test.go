@@ -0,0 +1,43 @@
+package notification
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDispatcherNotifyDedup(t *testing.T) {
+	// Dispatcher tanpa goroutine run agar isi antrean dapat diperiksa
+	d := &Dispatcher{
+		window: time.Hour,
+		queue:  make(chan Event, 1),
+		sent:   make(map[string]time.Time),
+	}
+	now := time.Now()
+	event := Event{Type: EventPrizeStockLow, Key: "prize:1:low", OccurredAt: now}
+
+	d.Notify(event)
+	d.Notify(event)
+	if got := len(d.queue); got != 1 {
+		t.Fatalf("queued %d events, want 1 after a duplicate", got)
+	}
+
+	// Event lain dibuang karena antrean penuh dan key-nya tidak dicatat
+	dropped := Event{Type: EventPrizeSoldOut, Key: "prize:1:sold_out", OccurredAt: now}
+	d.Notify(dropped)
+	if _, ok := d.sent[dropped.Key]; ok {
+		t.Fatal("key of a dropped event was recorded")
+	}
+
+	<-d.queue
+	d.Notify(dropped)
+	if got := len(d.queue); got != 1 {
+		t.Fatalf("queued %d events, want the dropped event to be retried", got)
+	}
+
+	<-d.queue
+	event.OccurredAt = now.Add(time.Hour)
+	d.Notify(event)
+	if got := len(d.queue); got != 1 {
+		t.Fatalf("queued %d events, want the event again after the window", got)
+	}
+}
@@ -0,0 +1,88 @@
+package notification
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// LogSink menulis event ke log
+type LogSink struct {
+	Logger *log.Logger // log bawaan dipakai jika nil
+}
+
+// Send menulis event ke log
+func (s LogSink) Send(event Event) error {
+	logf := log.Printf
+	if s.Logger != nil {
+		logf = s.Logger.Printf
+	}
+	logf("notification %s: %s - %s", event.Type, event.Subject, event.Message)
+	return nil
+}
+
+// WebhookSink mengirim event sebagai JSON dengan method POST ke URL
+type WebhookSink struct {
+	URL    string
+	Client *http.Client // http.Client dengan timeout 10 detik dipakai jika nil
+}
+
+// Send mengirim event ke webhook; respons selain 2xx dianggap gagal
+func (s WebhookSink) Send(event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	client := s.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Post(s.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// SMTPSink mengirim event sebagai email. Untuk pengujian lokal, Addr dapat
+// diarahkan ke server SMTP tiruan seperti MailHog ("localhost:1025") tanpa Username.
+type SMTPSink struct {
+	Addr     string // host:port
+	Username string // kosong jika server tidak memerlukan autentikasi
+	Password string
+	From     string
+	To       []string
+}
+
+// Send mengirim event sebagai email teks
+func (s SMTPSink) Send(event Event) error {
+	var auth smtp.Auth
+	if s.Username != "" {
+		host := s.Addr
+		if i := strings.LastIndex(host, ":"); i >= 0 {
+			host = host[:i]
+		}
+		auth = smtp.PlainAuth("", s.Username, s.Password, host)
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", s.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(s.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", event.Subject)
+	fmt.Fprintf(&msg, "Date: %s\r\n", event.OccurredAt.Format(time.RFC1123Z))
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	msg.WriteString(event.Message)
+	msg.WriteString("\r\n")
+
+	return smtp.SendMail(s.Addr, auth, s.From, s.To, msg.Bytes())
+}
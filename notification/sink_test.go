@@ -0,0 +1,126 @@
+package notification
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// smtpMessage adalah satu email yang diterima fakeSMTPServer
+type smtpMessage struct {
+	From string
+	To   []string
+	Data string
+}
+
+// fakeSMTPServer menjalankan server SMTP tiruan di localhost yang menerima
+// satu koneksi dan mengirim email yang diterimanya ke channel
+func fakeSMTPServer(t *testing.T) (string, <-chan smtpMessage) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	messages := make(chan smtpMessage, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+		reader := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+		reply("220 localhost ESMTP")
+
+		var msg smtpMessage
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			command := strings.ToUpper(line)
+			switch {
+			case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(command, "MAIL FROM:"):
+				msg.From = strings.Trim(line[len("MAIL FROM:"):], "<>")
+				reply("250 OK")
+			case strings.HasPrefix(command, "RCPT TO:"):
+				msg.To = append(msg.To, strings.Trim(line[len("RCPT TO:"):], "<>"))
+				reply("250 OK")
+			case command == "DATA":
+				reply("354 End data with <CR><LF>.<CR><LF>")
+				var data strings.Builder
+				for {
+					dataLine, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if dataLine == ".\r\n" {
+						break
+					}
+					data.WriteString(dataLine)
+				}
+				msg.Data = data.String()
+				messages <- msg
+				reply("250 OK")
+			case command == "QUIT":
+				reply("221 Bye")
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+
+	return listener.Addr().String(), messages
+}
+
+func TestSMTPSinkSend(t *testing.T) {
+	addr, messages := fakeSMTPServer(t)
+
+	sink := SMTPSink{
+		Addr: addr,
+		From: "alerts@example.com",
+		To:   []string{"ops@example.com", "stock@example.com"},
+	}
+	event := Event{
+		Type:       EventPrizeStockLow,
+		Key:        "prize:1:low",
+		Subject:    "Prize stock low",
+		Message:    "Only 3 vouchers left",
+		OccurredAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	if err := sink.Send(event); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	select {
+	case msg := <-messages:
+		if msg.From != sink.From {
+			t.Errorf("MAIL FROM = %q, want %q", msg.From, sink.From)
+		}
+		if strings.Join(msg.To, ",") != strings.Join(sink.To, ",") {
+			t.Errorf("RCPT TO = %v, want %v", msg.To, sink.To)
+		}
+		for _, want := range []string{
+			"To: ops@example.com, stock@example.com\r\n",
+			"Subject: Prize stock low\r\n",
+			"Only 3 vouchers left",
+		} {
+			if !strings.Contains(msg.Data, want) {
+				t.Errorf("message data %q does not contain %q", msg.Data, want)
+			}
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("SMTP server did not receive the message")
+	}
+}
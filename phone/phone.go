@@ -0,0 +1,127 @@
+// Package phone menormalkan nomor ponsel Indonesia ke format E.164
+// (+628xxxxxxxxx) dan mengenali operator selulernya dari prefix nomor.
+package phone
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// CountryCode adalah kode negara Indonesia
+const CountryCode = "62"
+
+const (
+	// minNationalLength dan maxNationalLength adalah panjang nomor ponsel
+	// tanpa kode negara maupun awalan 0, misalnya 8123456789
+	minNationalLength = 9
+	maxNationalLength = 12
+)
+
+var (
+	// ErrInvalidCharacter dikembalikan jika nomor mengandung karakter selain angka dan pemisah
+	ErrInvalidCharacter = errors.New("phone number contains invalid characters")
+	// ErrInvalidLength dikembalikan jika panjang nomor tidak sesuai nomor ponsel Indonesia
+	ErrInvalidLength = errors.New("phone number has an invalid length")
+	// ErrInvalidPrefix dikembalikan jika nomor bukan nomor ponsel Indonesia yang dikenal
+	ErrInvalidPrefix = errors.New("phone number has an unknown mobile prefix")
+)
+
+// Operator seluler
+const (
+	OperatorTelkomsel = "Telkomsel"
+	OperatorIndosat   = "Indosat Ooredoo"
+	OperatorXL        = "XL Axiata"
+	OperatorAxis      = "Axis"
+	OperatorTri       = "Tri"
+	OperatorSmartfren = "Smartfren"
+)
+
+// operators memetakan prefix nomor nasional (tanpa 0) ke operator
+var operators = map[string]string{
+	"811": OperatorTelkomsel,
+	"812": OperatorTelkomsel,
+	"813": OperatorTelkomsel,
+	"821": OperatorTelkomsel,
+	"822": OperatorTelkomsel,
+	"823": OperatorTelkomsel,
+	"851": OperatorTelkomsel,
+	"852": OperatorTelkomsel,
+	"853": OperatorTelkomsel,
+	"814": OperatorIndosat,
+	"815": OperatorIndosat,
+	"816": OperatorIndosat,
+	"855": OperatorIndosat,
+	"856": OperatorIndosat,
+	"857": OperatorIndosat,
+	"858": OperatorIndosat,
+	"817": OperatorXL,
+	"818": OperatorXL,
+	"819": OperatorXL,
+	"859": OperatorXL,
+	"877": OperatorXL,
+	"878": OperatorXL,
+	"831": OperatorAxis,
+	"832": OperatorAxis,
+	"833": OperatorAxis,
+	"838": OperatorAxis,
+	"895": OperatorTri,
+	"896": OperatorTri,
+	"897": OperatorTri,
+	"898": OperatorTri,
+	"899": OperatorTri,
+	"881": OperatorSmartfren,
+	"882": OperatorSmartfren,
+	"883": OperatorSmartfren,
+	"884": OperatorSmartfren,
+	"885": OperatorSmartfren,
+	"886": OperatorSmartfren,
+	"887": OperatorSmartfren,
+	"888": OperatorSmartfren,
+	"889": OperatorSmartfren,
+}
+
+// Number adalah nomor ponsel yang sudah dinormalkan
+type Number struct {
+	E164     string `json:"e164"`
+	Operator string `json:"operator"`
+}
+
+// Normalize menerima nomor seperti "0812...", "+62 812-...", "62812...",
+// "0062812..." atau "812..." dan mengembalikannya dalam format E.164 beserta operatornya
+func Normalize(input string) (Number, error) {
+	digits := strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '.', '(', ')':
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(input))
+	digits = strings.TrimPrefix(digits, "+")
+	// Awalan sambungan internasional 00 (0062...) setara dengan +
+	digits = strings.TrimPrefix(digits, "00")
+
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return Number{}, fmt.Errorf("%w: %q", ErrInvalidCharacter, r)
+		}
+	}
+
+	// Awalan 0 juga dibuang jika ditulis setelah kode negara, misalnya +62 0812...
+	national := strings.TrimPrefix(digits, CountryCode)
+	national = strings.TrimPrefix(national, "0")
+
+	if len(national) < minNationalLength || len(national) > maxNationalLength {
+		return Number{}, fmt.Errorf("%w: expected %d-%d digits after the country code, got %d", ErrInvalidLength, minNationalLength, maxNationalLength, len(national))
+	}
+
+	operator, ok := operators[national[:3]]
+	if !ok {
+		return Number{}, fmt.Errorf("%w 0%s", ErrInvalidPrefix, national[:3])
+	}
+
+	return Number{
+		E164:     "+" + CountryCode + national,
+		Operator: operator,
+	}, nil
+}
@@ -0,0 +1,59 @@
+package phone
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		input    string
+		e164     string
+		operator string
+	}{
+		{"081234567890", "+6281234567890", OperatorTelkomsel},
+		{"+6281234567890", "+6281234567890", OperatorTelkomsel},
+		{"6281234567890", "+6281234567890", OperatorTelkomsel},
+		{"006281234567890", "+6281234567890", OperatorTelkomsel},
+		{"81234567890", "+6281234567890", OperatorTelkomsel},
+		{"+62 0812-3456-7890", "+6281234567890", OperatorTelkomsel},
+		{" (0857) 1234.5678 ", "+6285712345678", OperatorIndosat},
+		{"0877 1234 567", "+628771234567", OperatorXL},
+		{"0838-1234-5678", "+6283812345678", OperatorAxis},
+		{"0896 1234 5678", "+6289612345678", OperatorTri},
+		{"0881 2345 6789", "+6288123456789", OperatorSmartfren},
+		// Panjang nomor nasional 9 dan 12 digit
+		{"0812345678", "+62812345678", OperatorTelkomsel},
+		{"0812345678901", "+62812345678901", OperatorTelkomsel},
+	}
+	for _, tt := range tests {
+		got, err := Normalize(tt.input)
+		if err != nil {
+			t.Errorf("Normalize(%q) error = %v", tt.input, err)
+			continue
+		}
+		if got.E164 != tt.e164 || got.Operator != tt.operator {
+			t.Errorf("Normalize(%q) = %+v, want %s %s", tt.input, got, tt.e164, tt.operator)
+		}
+	}
+}
+
+func TestNormalizeInvalid(t *testing.T) {
+	tests := []struct {
+		input string
+		want  error
+	}{
+		{"0812-3456-789a", ErrInvalidCharacter},
+		{"+62/81234567890", ErrInvalidCharacter},
+		{"081234567", ErrInvalidLength},
+		{"08123456789012", ErrInvalidLength},
+		{"", ErrInvalidLength},
+		{"0211234567", ErrInvalidPrefix},
+		{"00441234567890", ErrInvalidPrefix},
+	}
+	for _, tt := range tests {
+		if _, err := Normalize(tt.input); !errors.Is(err, tt.want) {
+			t.Errorf("Normalize(%q) error = %v, want %v", tt.input, err, tt.want)
+		}
+	}
+}
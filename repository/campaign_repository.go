@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gamaput/go-redeem/codeformat"
+	"github.com/gamaput/go-redeem/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrCodeFormatLocked dikembalikan jika format kode kampanye diubah padahal
+// kampanye sudah memiliki kode; kode satuan dan kode partner tidak menyimpan
+// formatnya sendiri dan selalu diperiksa dengan format kampanye saat ini
+var ErrCodeFormatLocked = errors.New("code format cannot be changed once the campaign has redeem codes")
+
+type campaignRepository struct {
+	DB *gorm.DB
+}
+
+// CampaignRepository : represent the campaign's repository contract
+type CampaignRepository interface {
+	CreateCampaign(model.Campaign) (model.Campaign, error)
+	GetCampaignByID(uint) (model.Campaign, error)
+	UpdateCampaign(model.Campaign) (model.Campaign, error)
+	DeleteCampaign(model.Campaign) (model.Campaign, error)
+	GetAllCampaigns() ([]model.Campaign, error)
+	GetCodeFormats() ([]codeformat.Spec, error)
+}
+
+// NewCampaignRepository -> returns new campaign repository
+func NewCampaignRepository(db *gorm.DB) CampaignRepository {
+	return campaignRepository{
+		DB: db,
+	}
+}
+
+func (cr campaignRepository) CreateCampaign(campaign model.Campaign) (model.Campaign, error) {
+	defer codeFormats.invalidate()
+	return campaign, cr.DB.Create(&campaign).Error
+}
+
+func (cr campaignRepository) GetCampaignByID(id uint) (campaign model.Campaign, err error) {
+	return campaign, cr.DB.First(&campaign, id).Error
+}
+
+func (cr campaignRepository) UpdateCampaign(campaign model.Campaign) (model.Campaign, error) {
+	defer codeFormats.invalidate()
+	err := cr.DB.Transaction(func(tx *gorm.DB) error {
+		var stored model.Campaign
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&stored, campaign.ID).Error; err != nil {
+			return err
+		}
+		if campaign.CodeSpec() != stored.CodeSpec() {
+			var codes int64
+			if err := tx.Model(&model.RedeemCode{}).Where("campaign_id = ?", campaign.ID).Count(&codes).Error; err != nil {
+				return err
+			}
+			if codes > 0 {
+				return ErrCodeFormatLocked
+			}
+		}
+		return tx.Model(&model.Campaign{}).Where("id = ?", campaign.ID).Updates(map[string]interface{}{
+			"name":            campaign.Name,
+			"start_at":        campaign.StartAt,
+			"end_at":          campaign.EndAt,
+			"status":          campaign.Status,
+			"no_prize_weight": campaign.NoPrizeWeight,
+			"code_length":     campaign.CodeFormat.Length,
+			"code_alphabet":   campaign.CodeFormat.Alphabet,
+			"code_prefix":     campaign.CodeFormat.Prefix,
+			"code_group_size": campaign.CodeFormat.GroupSize,
+			"code_check":      campaign.CodeFormat.Check,
+		}).Error
+	})
+	return campaign, err
+}
+
+func (cr campaignRepository) DeleteCampaign(campaign model.Campaign) (model.Campaign, error) {
+	defer codeFormats.invalidate()
+	if err := cr.DB.First(&campaign, campaign.ID).Error; err != nil {
+		return campaign, err
+	}
+	return campaign, cr.DB.Delete(&campaign).Error
+}
+
+func (cr campaignRepository) GetAllCampaigns() (campaigns []model.Campaign, err error) {
+	return campaigns, cr.DB.Find(&campaigns).Error
+}
+
+// codeFormatCacheTTL membatasi berapa lama format dari instance lain bisa tertinggal
+const codeFormatCacheTTL = time.Minute
+
+// codeFormatCache menyimpan hasil GetCodeFormats, yang dipakai pada setiap
+// request publik. Cache dikosongkan saat kampanye atau batch kode berubah.
+type codeFormatCache struct {
+	mu       sync.Mutex
+	specs    []codeformat.Spec
+	loadedAt time.Time
+}
+
+var codeFormats = &codeFormatCache{}
+
+func (c *codeFormatCache) get(load func() ([]codeformat.Spec, error)) ([]codeformat.Spec, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.specs != nil && time.Since(c.loadedAt) < codeFormatCacheTTL {
+		return c.specs, nil
+	}
+	specs, err := load()
+	if err != nil {
+		return nil, err
+	}
+	c.specs, c.loadedAt = specs, time.Now()
+	return specs, nil
+}
+
+func (c *codeFormatCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.specs = nil
+}
+
+// GetCodeFormats mengembalikan semua format kode dari semua kampanye beserta
+// batch-batchnya tanpa duplikat, termasuk kampanye yang tidak aktif, sehingga
+// kode dari kampanye yang dijeda atau sudah berakhir tetap dikenali
+func (cr campaignRepository) GetCodeFormats() ([]codeformat.Spec, error) {
+	return codeFormats.get(cr.loadCodeFormats)
+}
+
+func (cr campaignRepository) loadCodeFormats() ([]codeformat.Spec, error) {
+	var campaigns []model.Campaign
+	if err := cr.DB.Find(&campaigns).Error; err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, 0, len(campaigns))
+	specs := make([]codeformat.Spec, 0, len(campaigns))
+	seen := make(map[codeformat.Spec]bool)
+	for _, campaign := range campaigns {
+		ids = append(ids, campaign.ID)
+		if spec := campaign.CodeSpec(); !seen[spec] {
+			seen[spec] = true
+			specs = append(specs, spec)
+		}
+	}
+	if len(ids) == 0 {
+		return specs, nil
+	}
+
+	var batches []model.CodeBatch
+	if err := cr.DB.Where("campaign_id IN ?", ids).Find(&batches).Error; err != nil {
+		return nil, err
+	}
+	for _, batch := range batches {
+		if spec := batch.CodeFormat.OrDefault(codeformat.Legacy); !seen[spec] {
+			seen[spec] = true
+			specs = append(specs, spec)
+		}
+	}
+	return specs, nil
+}
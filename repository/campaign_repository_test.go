@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gamaput/go-redeem/codeformat"
+	"github.com/gamaput/go-redeem/model"
+)
+
+func TestUpdateCampaignCodeFormatLocked(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewCampaignRepository(db)
+
+	campaign, err := repo.CreateCampaign(model.Campaign{
+		Name:       "Ramadan",
+		StartAt:    time.Now(),
+		EndAt:      time.Now().Add(time.Hour),
+		Status:     model.CampaignStatusActive,
+		CodeFormat: codeformat.Default,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Tanpa kode, format masih boleh diubah
+	changed := campaign
+	changed.CodeFormat.Prefix = "RMD"
+	if _, err := repo.UpdateCampaign(changed); err != nil {
+		t.Fatalf("UpdateCampaign(no codes) error = %v", err)
+	}
+
+	if err := db.Create(&model.RedeemCode{CampaignID: campaign.ID, Code: "RMD1234567A"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	changed.CodeFormat.Prefix = "PROMO"
+	if _, err := repo.UpdateCampaign(changed); !errors.Is(err, ErrCodeFormatLocked) {
+		t.Fatalf("UpdateCampaign(format change with codes) error = %v, want %v", err, ErrCodeFormatLocked)
+	}
+
+	// Field lain tetap bisa diubah selama formatnya sama
+	changed.CodeFormat.Prefix = "RMD"
+	changed.Name = "Ramadan 2026"
+	if _, err := repo.UpdateCampaign(changed); err != nil {
+		t.Fatalf("UpdateCampaign(same format) error = %v", err)
+	}
+	stored, err := repo.GetCampaignByID(campaign.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Name != "Ramadan 2026" || stored.CodeFormat.Prefix != "RMD" {
+		t.Errorf("stored campaign = %+v, want new name and prefix RMD", stored)
+	}
+}
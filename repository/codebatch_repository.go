@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/gamaput/go-redeem/codeformat"
+	"github.com/gamaput/go-redeem/model"
+	"github.com/gamaput/go-redeem/utils"
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+const (
+	// codeBatchChunkSize adalah jumlah kode yang dibuat dan di-insert per query
+	codeBatchChunkSize = 1000
+	// maxChunkAttempts membatasi percobaan ulang satu chunk yang bentrok dengan kode lain
+	maxChunkAttempts = 5
+)
+
+type codeBatchRepository struct {
+	DB *gorm.DB
+}
+
+// CodeBatchRepository : represent the code batch's repository contract
+type CodeBatchRepository interface {
+	CreateCodeBatch(batch *model.CodeBatch) error
+	GetCodeBatchByID(uint) (model.CodeBatch, error)
+	UpdateCodeBatchValidity(batch model.CodeBatch) error
+}
+
+// NewCodeBatchRepository -> returns new code batch repository
+func NewCodeBatchRepository(db *gorm.DB) CodeBatchRepository {
+	return codeBatchRepository{
+		DB: db,
+	}
+}
+
+// CreateCodeBatch menyimpan batch dan membuat batch.Count kode redeem unik
+// untuk kampanye batch dalam satu transaksi. Kode di-insert per chunk;
+// kode yang sudah ada di database diganti sebelum insert, dan chunk yang
+// tetap bentrok dengan unique index (misalnya karena batch lain yang berjalan
+// bersamaan) dibuat ulang.
+func (br codeBatchRepository) CreateCodeBatch(batch *model.CodeBatch) error {
+	defer codeFormats.invalidate()
+	return br.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(batch).Error; err != nil {
+			return err
+		}
+
+		seen := make(map[string]struct{}, batch.Count)
+		for remaining := batch.Count; remaining > 0; {
+			size := codeBatchChunkSize
+			if remaining < size {
+				size = remaining
+			}
+			if err := insertCodeChunk(tx, batch, size, seen); err != nil {
+				return err
+			}
+			remaining -= size
+		}
+
+		return enqueueOutboxEvent(tx, model.EventCodeBatchGenerated, map[string]interface{}{
+			"batch_id":    batch.ID,
+			"campaign_id": batch.CampaignID,
+			"label":       batch.Label,
+			"count":       batch.Count,
+			"valid_from":  batch.ValidFrom,
+			"valid_until": batch.ValidUntil,
+			"created_by":  batch.CreatedBy,
+		})
+	})
+}
+
+func insertCodeChunk(tx *gorm.DB, batch *model.CodeBatch, size int, seen map[string]struct{}) error {
+	for attempt := 0; attempt < maxChunkAttempts; attempt++ {
+		codes, err := newUniqueCodes(tx, batch.CodeFormat, size, seen)
+		if err != nil {
+			return err
+		}
+
+		redeemCodes := make([]model.RedeemCode, 0, len(codes))
+		for _, code := range codes {
+			redeemCodes = append(redeemCodes, model.RedeemCode{
+				CampaignID:       batch.CampaignID,
+				BatchID:          batch.ID,
+				Code:             code,
+				ValidFrom:        batch.ValidFrom,
+				ValidUntil:       batch.ValidUntil,
+				MaxUses:          batch.MaxUses,
+				PerIdentityLimit: batch.PerIdentityLimit,
+			})
+		}
+
+		err = tx.Create(&redeemCodes).Error
+		if err == nil {
+			return nil
+		}
+		if !isDuplicateKeyError(err) {
+			return err
+		}
+		for _, code := range codes {
+			delete(seen, code)
+		}
+	}
+	return utils.ErrCodeSpaceExhausted
+}
+
+// newUniqueCodes membuat size kode yang belum ada di seen maupun di database
+func newUniqueCodes(tx *gorm.DB, spec codeformat.Spec, size int, seen map[string]struct{}) ([]string, error) {
+	codes := make([]string, 0, size)
+	for attempt := 0; len(codes) < size; attempt++ {
+		if attempt == maxChunkAttempts {
+			return nil, utils.ErrCodeSpaceExhausted
+		}
+
+		candidates := make([]string, 0, size-len(codes))
+		for len(candidates) < size-len(codes) {
+			code, err := spec.Generate()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := seen[code]; ok {
+				continue
+			}
+			seen[code] = struct{}{}
+			candidates = append(candidates, code)
+		}
+
+		var existing []string
+		if err := tx.Unscoped().Model(&model.RedeemCode{}).Where("code IN ?", candidates).Pluck("code", &existing).Error; err != nil {
+			return nil, err
+		}
+		taken := make(map[string]struct{}, len(existing))
+		for _, code := range existing {
+			taken[code] = struct{}{}
+		}
+
+		for _, code := range candidates {
+			if _, ok := taken[code]; !ok {
+				codes = append(codes, code)
+			}
+		}
+	}
+	return codes, nil
+}
+
+// isDuplicateKeyError memeriksa apakah err adalah pelanggaran unique index MySQL
+func isDuplicateKeyError(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1062
+}
+
+func (br codeBatchRepository) GetCodeBatchByID(id uint) (batch model.CodeBatch, err error) {
+	return batch, br.DB.First(&batch, id).Error
+}
+
+// UpdateCodeBatchValidity menyimpan masa berlaku batch dan menerapkannya ke
+// semua kode dalam batch tersebut
+func (br codeBatchRepository) UpdateCodeBatchValidity(batch model.CodeBatch) error {
+	return br.DB.Transaction(func(tx *gorm.DB) error {
+		validity := map[string]interface{}{
+			"valid_from":  batch.ValidFrom,
+			"valid_until": batch.ValidUntil,
+		}
+		if err := tx.Model(&model.CodeBatch{}).Where("id = ?", batch.ID).Updates(validity).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.RedeemCode{}).Where("batch_id = ?", batch.ID).Updates(validity).Error; err != nil {
+			return err
+		}
+		// Kode yang sudah ditandai expired diaktifkan kembali; kode yang masih
+		// di luar masa berlaku baru akan ditandai expired lagi saat ditukarkan
+		return tx.Model(&model.RedeemCode{}).Where("batch_id = ? AND status = ?", batch.ID, model.CodeStatusExpired).
+			Update("status", model.CodeStatusActive).Error
+	})
+}
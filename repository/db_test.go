@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gamaput/go-redeem/model"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testModels adalah tabel yang dibuat ulang untuk setiap test
+var testModels = []interface{}{
+	&model.Campaign{}, &model.RedeemCode{}, &model.Participant{}, &model.Redemption{},
+	&model.Prize{}, &model.InventoryEntry{}, &model.OutboxEvent{}, &model.User{}, &model.UserToken{},
+	&model.UsedMFAChallenge{}, &model.RoleGrant{},
+}
+
+// newTestDB membuka database kosong untuk test. Jika TEST_MYSQL_DSN diisi,
+// test memakai MySQL sehingga penguncian baris (SELECT ... FOR UPDATE) ikut
+// diuji; selain itu dipakai SQLite yang menjalankan transaksi satu per satu.
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	config := &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)}
+
+	var db *gorm.DB
+	var err error
+	if dsn := os.Getenv("TEST_MYSQL_DSN"); dsn != "" {
+		db, err = gorm.Open(mysql.Open(dsn), config)
+		if err == nil {
+			err = db.Migrator().DropTable(testModels...)
+		}
+	} else {
+		dir, dirErr := ioutil.TempDir("", "go-redeem-test")
+		if dirErr != nil {
+			t.Fatal(dirErr)
+		}
+		t.Cleanup(func() { os.RemoveAll(dir) })
+		dsn := filepath.Join(dir, "test.db") + "?_txlock=immediate&_busy_timeout=30000&_journal_mode=WAL"
+		db, err = gorm.Open(sqlite.Open(dsn), config)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(testModels...); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gamaput/go-redeem/model"
+	"gorm.io/gorm"
+)
+
+type idempotencyRepository struct {
+	DB *gorm.DB
+}
+
+// IdempotencyRepository : represent the idempotency key's repository contract
+type IdempotencyRepository interface {
+	BeginIdempotentRequest(*model.IdempotencyKey) (model.IdempotencyKey, bool, error)
+	CompleteIdempotentRequest(id uint, statusCode int, contentType string, response []byte) error
+	ReleaseIdempotentRequest(id uint) error
+	DeleteExpiredIdempotencyKeys(now time.Time) (int64, error)
+}
+
+// NewIdempotencyRepository -> returns new idempotency key repository
+func NewIdempotencyRepository(db *gorm.DB) IdempotencyRepository {
+	return idempotencyRepository{
+		DB: db,
+	}
+}
+
+// BeginIdempotentRequest menyimpan key untuk request baru. Jika key dengan scope
+// yang sama sudah ada dan belum kedaluwarsa, record tersebut dikembalikan dengan
+// created bernilai false.
+func (ir idempotencyRepository) BeginIdempotentRequest(key *model.IdempotencyKey) (existing model.IdempotencyKey, created bool, err error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		err = ir.DB.Create(key).Error
+		if err == nil {
+			return *key, true, nil
+		}
+		if !isDuplicateKeyError(err) {
+			return existing, false, err
+		}
+
+		err = ir.DB.Unscoped().Where("scope = ? AND `key` = ?", key.Scope, key.Key).First(&existing).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Key dihapus oleh request lain di antara insert dan select
+			continue
+		}
+		if err != nil {
+			return existing, false, err
+		}
+		if existing.DeletedAt.Valid || !existing.ExpiresAt.After(time.Now()) {
+			// Key yang kedaluwarsa boleh dipakai ulang untuk request baru
+			if err := ir.DB.Unscoped().Where("id = ? AND expires_at = ?", existing.ID, existing.ExpiresAt).Delete(&model.IdempotencyKey{}).Error; err != nil {
+				return existing, false, err
+			}
+			key.ID = 0
+			continue
+		}
+		return existing, false, nil
+	}
+	return existing, false, errors.New("failed to reserve idempotency key")
+}
+
+// CompleteIdempotentRequest menyimpan respons request pertama untuk diputar ulang
+func (ir idempotencyRepository) CompleteIdempotentRequest(id uint, statusCode int, contentType string, response []byte) error {
+	return ir.DB.Model(&model.IdempotencyKey{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"completed":    true,
+		"status_code":  statusCode,
+		"content_type": contentType,
+		"response":     response,
+	}).Error
+}
+
+// ReleaseIdempotentRequest menghapus key agar request dengan key yang sama dapat dicoba lagi
+func (ir idempotencyRepository) ReleaseIdempotentRequest(id uint) error {
+	return ir.DB.Unscoped().Delete(&model.IdempotencyKey{}, id).Error
+}
+
+// DeleteExpiredIdempotencyKeys menghapus key yang sudah melewati TTL
+func (ir idempotencyRepository) DeleteExpiredIdempotencyKeys(now time.Time) (int64, error) {
+	result := ir.DB.Unscoped().Where("expires_at <= ?", now).Delete(&model.IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,254 @@
+package repository
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/gamaput/go-redeem/model"
+	"github.com/gamaput/go-redeem/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrLoginThrottleNotFound dikembalikan jika lockout yang akan dibuka tidak ada
+var ErrLoginThrottleNotFound = errors.New("lockout not found")
+
+// LoginLockoutPolicy mengatur lockout sign in: setelah MaxFailures kegagalan
+// dalam Window, key dikunci selama BaseDuration, lalu dua kali lipat untuk
+// setiap kegagalan berikutnya hingga MaxDuration
+type LoginLockoutPolicy struct {
+	MaxFailures  int
+	Window       time.Duration
+	BaseDuration time.Duration
+	MaxDuration  time.Duration
+}
+
+// lockDuration mengembalikan lama lockout setelah failures kegagalan
+func (p LoginLockoutPolicy) lockDuration(failures int) time.Duration {
+	if p.MaxFailures <= 0 || failures < p.MaxFailures {
+		return 0
+	}
+	d := time.Duration(float64(p.BaseDuration) * math.Pow(2, float64(failures-p.MaxFailures)))
+	if p.MaxDuration > 0 && (d > p.MaxDuration || d <= 0) {
+		d = p.MaxDuration
+	}
+	return d
+}
+
+// LoginAttempt adalah satu percobaan sign in
+type LoginAttempt struct {
+	Email    string
+	UserID   uint // 0 jika email tidak terdaftar
+	ClientIP string
+}
+
+// throttleKey mengembalikan key untuk satu cakupan percobaan sign in
+func (a LoginAttempt) throttleKey(scope string) string {
+	value := a.ClientIP
+	if scope == model.LoginThrottleAccount {
+		value = strings.ToLower(strings.TrimSpace(a.Email))
+	}
+	key := scope + ":" + value
+	if len(key) > 191 {
+		key = scope + ":" + utils.HashToken(value)
+	}
+	return key
+}
+
+type loginThrottleRepository struct {
+	DB       *gorm.DB
+	policies map[string]LoginLockoutPolicy
+}
+
+// LoginThrottleRepository : represent the sign in throttle repository contract
+type LoginThrottleRepository interface {
+	LoginLockedFor(attempt LoginAttempt, now time.Time) (time.Duration, error)
+	RecordLoginFailure(attempt LoginAttempt, now time.Time) (time.Duration, error)
+	RecordLoginSuccess(attempt LoginAttempt) error
+	GetActiveLockouts(now time.Time) ([]model.LoginThrottle, error)
+	GetLockoutEvents(userID uint, limit int) ([]model.LoginLockoutEvent, error)
+	Unlock(id, actorID uint, reason string) (model.LoginThrottle, error)
+}
+
+// NewLoginThrottleRepository -> returns new sign in throttle repository with
+// separate lockout policies per account and per client IP
+func NewLoginThrottleRepository(db *gorm.DB, account, ip LoginLockoutPolicy) LoginThrottleRepository {
+	return loginThrottleRepository{
+		DB: db,
+		policies: map[string]LoginLockoutPolicy{
+			model.LoginThrottleAccount: account,
+			model.LoginThrottleIP:      ip,
+		},
+	}
+}
+
+// LoginLockedFor mengembalikan sisa lockout terlama dari akun dan IP attempt,
+// 0 jika keduanya tidak terkunci
+func (lr loginThrottleRepository) LoginLockedFor(attempt LoginAttempt, now time.Time) (time.Duration, error) {
+	var throttles []model.LoginThrottle
+	if err := lr.DB.Where("throttle_key IN ?", []string{
+		attempt.throttleKey(model.LoginThrottleAccount),
+		attempt.throttleKey(model.LoginThrottleIP),
+	}).Find(&throttles).Error; err != nil {
+		return 0, err
+	}
+	var locked time.Duration
+	for _, throttle := range throttles {
+		if d := throttle.LockedFor(now); d > locked {
+			locked = d
+		}
+	}
+	return locked, nil
+}
+
+// RecordLoginFailure menambah hitungan kegagalan akun dan IP attempt, mengunci
+// key yang melewati batas, dan mengembalikan lama lockout terlama yang berlaku
+func (lr loginThrottleRepository) RecordLoginFailure(attempt LoginAttempt, now time.Time) (locked time.Duration, err error) {
+	err = lr.DB.Transaction(func(tx *gorm.DB) error {
+		for _, scope := range []string{model.LoginThrottleAccount, model.LoginThrottleIP} {
+			d, err := lr.recordFailure(tx, attempt, scope, now)
+			if err != nil {
+				return err
+			}
+			if d > locked {
+				locked = d
+			}
+		}
+		return nil
+	})
+	return locked, err
+}
+
+func (lr loginThrottleRepository) recordFailure(tx *gorm.DB, attempt LoginAttempt, scope string, now time.Time) (time.Duration, error) {
+	key := attempt.throttleKey(scope)
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.LoginThrottle{ThrottleKey: key, Scope: scope}).Error; err != nil {
+		return 0, err
+	}
+	var throttle model.LoginThrottle
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("throttle_key = ?", key).First(&throttle).Error; err != nil {
+		return 0, err
+	}
+
+	// Hitungan dimulai ulang jika tidak ada kegagalan maupun lockout selama Window
+	policy := lr.policies[scope]
+	lastActivity := throttle.LastFailureAt
+	if throttle.LockedUntil != nil && (lastActivity == nil || throttle.LockedUntil.After(*lastActivity)) {
+		lastActivity = throttle.LockedUntil
+	}
+	if lastActivity == nil || now.Sub(*lastActivity) > policy.Window {
+		throttle.Failures = 0
+	}
+	throttle.Failures++
+	throttle.LastFailureAt = &now
+	if scope == model.LoginThrottleAccount && attempt.UserID != 0 {
+		throttle.UserID = attempt.UserID
+	}
+
+	lockFor := policy.lockDuration(throttle.Failures)
+	if lockFor > 0 {
+		lockedUntil := now.Add(lockFor)
+		throttle.LockedUntil = &lockedUntil
+	}
+	if err := tx.Model(&model.LoginThrottle{}).Where("id = ?", throttle.ID).Updates(map[string]interface{}{
+		"user_id":         throttle.UserID,
+		"failures":        throttle.Failures,
+		"last_failure_at": throttle.LastFailureAt,
+		"locked_until":    throttle.LockedUntil,
+	}).Error; err != nil {
+		return 0, err
+	}
+	if lockFor == 0 {
+		return 0, nil
+	}
+	return lockFor, tx.Create(&model.LoginLockoutEvent{
+		ThrottleKey: key,
+		Scope:       scope,
+		UserID:      throttle.UserID,
+		Event:       model.LoginLockoutLocked,
+		Failures:    throttle.Failures,
+		LockedUntil: throttle.LockedUntil,
+		ClientIP:    attempt.ClientIP,
+		Reason:      "too many failed sign in attempts",
+	}).Error
+}
+
+// RecordLoginSuccess menghapus hitungan kegagalan akun. Hitungan per IP
+// tidak dihapus, agar sign in ke akun sendiri tidak bisa dipakai untuk
+// menebak password akun lain dari IP yang sama.
+func (lr loginThrottleRepository) RecordLoginSuccess(attempt LoginAttempt) error {
+	return lr.DB.Model(&model.LoginThrottle{}).
+		Where("throttle_key = ? AND failures > 0", attempt.throttleKey(model.LoginThrottleAccount)).
+		Updates(map[string]interface{}{"failures": 0, "locked_until": nil}).Error
+}
+
+// GetActiveLockouts mengembalikan akun dan IP yang sedang terkunci
+func (lr loginThrottleRepository) GetActiveLockouts(now time.Time) (throttles []model.LoginThrottle, err error) {
+	return throttles, lr.DB.Where("locked_until > ?", now).Order("locked_until DESC").Find(&throttles).Error
+}
+
+// GetLockoutEvents mengembalikan riwayat lockout dan pembukaannya, terbaru
+// lebih dulu, untuk userID tertentu atau semua jika userID 0
+func (lr loginThrottleRepository) GetLockoutEvents(userID uint, limit int) (events []model.LoginLockoutEvent, err error) {
+	query := lr.DB.Order("id DESC").Limit(limit)
+	if userID != 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+	return events, query.Find(&events).Error
+}
+
+// Unlock membuka lockout akun atau IP dan mencatat admin yang membukanya
+func (lr loginThrottleRepository) Unlock(id, actorID uint, reason string) (throttle model.LoginThrottle, err error) {
+	err = lr.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&throttle, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrLoginThrottleNotFound
+			}
+			return err
+		}
+		return unlockThrottle(tx, &throttle, actorID, reason)
+	})
+	return throttle, err
+}
+
+// unlockAccountThrottles membuka lockout akun milik userID, misalnya setelah reset password
+func unlockAccountThrottles(tx *gorm.DB, userID uint, reason string) error {
+	var throttles []model.LoginThrottle
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("scope = ? AND user_id = ? AND failures > 0", model.LoginThrottleAccount, userID).
+		Find(&throttles).Error; err != nil {
+		return err
+	}
+	for i := range throttles {
+		if err := unlockThrottle(tx, &throttles[i], 0, reason); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unlockThrottle menghapus hitungan kegagalan dan lockout throttle, dan
+// mencatat event unlocked jika throttle sedang terkunci
+func unlockThrottle(tx *gorm.DB, throttle *model.LoginThrottle, actorID uint, reason string) error {
+	wasLocked := throttle.LockedFor(time.Now()) > 0
+	failures := throttle.Failures
+	throttle.Failures = 0
+	throttle.LockedUntil = nil
+	if err := tx.Model(&model.LoginThrottle{}).Where("id = ?", throttle.ID).
+		Updates(map[string]interface{}{"failures": 0, "locked_until": nil}).Error; err != nil {
+		return err
+	}
+	if !wasLocked {
+		return nil
+	}
+	return tx.Create(&model.LoginLockoutEvent{
+		ThrottleKey: throttle.ThrottleKey,
+		Scope:       throttle.Scope,
+		UserID:      throttle.UserID,
+		Event:       model.LoginLockoutUnlocked,
+		Failures:    failures,
+		ActorID:     actorID,
+		Reason:      reason,
+	}).Error
+}
@@ -0,0 +1,222 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gamaput/go-redeem/model"
+	"github.com/gamaput/go-redeem/totp"
+	"github.com/gamaput/go-redeem/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrTOTPNotEnabled dikembalikan jika user belum mengaktifkan 2FA
+	ErrTOTPNotEnabled = errors.New("two-factor authentication is not enabled")
+	// ErrTOTPNotEnrolled dikembalikan jika user belum memulai pendaftaran TOTP
+	ErrTOTPNotEnrolled = errors.New("two-factor authentication enrollment not started")
+	// ErrTOTPAlreadyEnabled dikembalikan jika user sudah mengaktifkan 2FA
+	ErrTOTPAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	// ErrTOTPCodeInvalid dikembalikan jika kode TOTP salah atau sudah dipakai
+	ErrTOTPCodeInvalid = errors.New("invalid two-factor code")
+	// ErrRecoveryCodeInvalid dikembalikan jika recovery code tidak dikenal atau sudah dipakai
+	ErrRecoveryCodeInvalid = errors.New("invalid recovery code")
+	// ErrMFAChallengeUsed dikembalikan jika challenge token 2FA sudah pernah ditukar
+	ErrMFAChallengeUsed = errors.New("mfa token has already been used")
+)
+
+type mfaRepository struct {
+	DB *gorm.DB
+}
+
+// MFARepository : represent the two-factor authentication repository contract
+type MFARepository interface {
+	GetTOTP(userID uint) (model.UserTOTP, error)
+	BeginTOTPEnrollment(userID uint, secret string) error
+	ConfirmTOTP(userID uint, code string, recoveryCodes []string) error
+	VerifyTOTP(userID uint, code string) error
+	UseRecoveryCode(userID uint, code string) error
+	ReplaceRecoveryCodes(userID uint, recoveryCodes []string) error
+	CountRecoveryCodes(userID uint) (int64, error)
+	DisableTOTP(userID uint) error
+	UseMFAChallenge(challenge utils.MFAChallenge) error
+	DeleteExpiredMFAChallenges(now time.Time) (int64, error)
+}
+
+// NewMFARepository -> returns new two-factor authentication repository
+func NewMFARepository(db *gorm.DB) MFARepository {
+	return mfaRepository{
+		DB: db,
+	}
+}
+
+// GetTOTP mengembalikan secret TOTP user, terkonfirmasi maupun belum
+func (mr mfaRepository) GetTOTP(userID uint) (userTOTP model.UserTOTP, err error) {
+	err = mr.DB.Where("user_id = ?", userID).First(&userTOTP).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return userTOTP, ErrTOTPNotEnrolled
+	}
+	return userTOTP, err
+}
+
+// BeginTOTPEnrollment menyimpan secret baru yang belum terkonfirmasi,
+// menggantikan pendaftaran sebelumnya yang belum selesai
+func (mr mfaRepository) BeginTOTPEnrollment(userID uint, secret string) error {
+	return mr.DB.Transaction(func(tx *gorm.DB) error {
+		var existing model.UserTOTP
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", userID).First(&existing).Error
+		switch {
+		case err == nil && existing.ConfirmedAt != nil:
+			return ErrTOTPAlreadyEnabled
+		case err == nil:
+			return tx.Model(&model.UserTOTP{}).Where("id = ?", existing.ID).Updates(map[string]interface{}{
+				"secret":    secret,
+				"last_step": 0,
+			}).Error
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return err
+		}
+		return tx.Create(&model.UserTOTP{UserID: userID, Secret: secret}).Error
+	})
+}
+
+// ConfirmTOTP mengaktifkan 2FA jika code cocok dengan secret yang sedang
+// didaftarkan, dan menyimpan hash recoveryCodes
+func (mr mfaRepository) ConfirmTOTP(userID uint, code string, recoveryCodes []string) error {
+	return mr.DB.Transaction(func(tx *gorm.DB) error {
+		userTOTP, err := lockTOTP(tx, userID)
+		if err != nil {
+			return err
+		}
+		if userTOTP.ConfirmedAt != nil {
+			return ErrTOTPAlreadyEnabled
+		}
+		now := time.Now()
+		step, ok := totp.Validate(userTOTP.Secret, code, now, userTOTP.LastStep)
+		if !ok {
+			return ErrTOTPCodeInvalid
+		}
+		if err := tx.Model(&model.UserTOTP{}).Where("id = ?", userTOTP.ID).Updates(map[string]interface{}{
+			"confirmed_at": now,
+			"last_step":    step,
+		}).Error; err != nil {
+			return err
+		}
+		return replaceRecoveryCodes(tx, userID, recoveryCodes)
+	})
+}
+
+// VerifyTOTP memeriksa kode TOTP user yang sudah mengaktifkan 2FA. Kode
+// yang sudah pernah diterima ditolak.
+func (mr mfaRepository) VerifyTOTP(userID uint, code string) error {
+	return mr.DB.Transaction(func(tx *gorm.DB) error {
+		userTOTP, err := lockTOTP(tx, userID)
+		if errors.Is(err, ErrTOTPNotEnrolled) || (err == nil && userTOTP.ConfirmedAt == nil) {
+			return ErrTOTPNotEnabled
+		}
+		if err != nil {
+			return err
+		}
+		step, ok := totp.Validate(userTOTP.Secret, code, time.Now(), userTOTP.LastStep)
+		if !ok {
+			return ErrTOTPCodeInvalid
+		}
+		return tx.Model(&model.UserTOTP{}).Where("id = ?", userTOTP.ID).Update("last_step", step).Error
+	})
+}
+
+// UseRecoveryCode memakai satu recovery code milik user
+func (mr mfaRepository) UseRecoveryCode(userID uint, code string) error {
+	result := mr.DB.Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, utils.HashToken(totp.NormalizeRecoveryCode(code))).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrRecoveryCodeInvalid
+	}
+	return nil
+}
+
+// UseMFAChallenge mencatat challenge token 2FA sebagai sudah ditukar. Token
+// yang jti-nya sudah tercatat ditolak dengan ErrMFAChallengeUsed.
+func (mr mfaRepository) UseMFAChallenge(challenge utils.MFAChallenge) error {
+	return mr.DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&model.UsedMFAChallenge{}).Where("jti = ?", challenge.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrMFAChallengeUsed
+		}
+		err := tx.Create(&model.UsedMFAChallenge{
+			JTI:       challenge.ID,
+			UserID:    challenge.UserID,
+			ExpiresAt: challenge.ExpiresAt,
+		}).Error
+		// Penukaran bersamaan dengan jti yang sama ditolak oleh unique index
+		if isDuplicateKeyError(err) {
+			return ErrMFAChallengeUsed
+		}
+		return err
+	})
+}
+
+// DeleteExpiredMFAChallenges menghapus catatan challenge token yang sudah kedaluwarsa
+func (mr mfaRepository) DeleteExpiredMFAChallenges(now time.Time) (int64, error) {
+	result := mr.DB.Where("expires_at <= ?", now).Delete(&model.UsedMFAChallenge{})
+	return result.RowsAffected, result.Error
+}
+
+// ReplaceRecoveryCodes mengganti semua recovery code user dengan recoveryCodes
+func (mr mfaRepository) ReplaceRecoveryCodes(userID uint, recoveryCodes []string) error {
+	return mr.DB.Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, userID, recoveryCodes)
+	})
+}
+
+// CountRecoveryCodes mengembalikan jumlah recovery code yang belum dipakai
+func (mr mfaRepository) CountRecoveryCodes(userID uint) (count int64, err error) {
+	return count, mr.DB.Model(&model.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).Count(&count).Error
+}
+
+// DisableTOTP menghapus secret TOTP dan recovery code user
+func (mr mfaRepository) DisableTOTP(userID uint) error {
+	return mr.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().Where("user_id = ?", userID).Delete(&model.UserTOTP{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrTOTPNotEnabled
+		}
+		return tx.Unscoped().Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error
+	})
+}
+
+// lockTOTP mengunci secret TOTP user sampai transaksi selesai, agar satu kode
+// tidak bisa diterima dua kali oleh request yang bersamaan
+func lockTOTP(tx *gorm.DB, userID uint) (userTOTP model.UserTOTP, err error) {
+	err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", userID).First(&userTOTP).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return userTOTP, ErrTOTPNotEnrolled
+	}
+	return userTOTP, err
+}
+
+// replaceRecoveryCodes menghapus recovery code lama user dan menyimpan hash recoveryCodes
+func replaceRecoveryCodes(tx *gorm.DB, userID uint, recoveryCodes []string) error {
+	if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+	if len(recoveryCodes) == 0 {
+		return nil
+	}
+	rows := make([]model.RecoveryCode, len(recoveryCodes))
+	for i, code := range recoveryCodes {
+		rows[i] = model.RecoveryCode{UserID: userID, CodeHash: utils.HashToken(totp.NormalizeRecoveryCode(code))}
+	}
+	return tx.Create(&rows).Error
+}
@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gamaput/go-redeem/utils"
+)
+
+func TestUseMFAChallengeSingleUse(t *testing.T) {
+	repo := NewMFARepository(newTestDB(t))
+	now := time.Now()
+	challenge := utils.MFAChallenge{ID: "challenge-1", UserID: 1, ExpiresAt: now.Add(utils.MFATokenTTL)}
+
+	if err := repo.UseMFAChallenge(challenge); err != nil {
+		t.Fatalf("UseMFAChallenge() error = %v", err)
+	}
+	if err := repo.UseMFAChallenge(challenge); !errors.Is(err, ErrMFAChallengeUsed) {
+		t.Fatalf("UseMFAChallenge(replay) error = %v, want %v", err, ErrMFAChallengeUsed)
+	}
+
+	// Catatan yang sudah kedaluwarsa dihapus
+	deleted, err := repo.DeleteExpiredMFAChallenges(now.Add(utils.MFATokenTTL))
+	if err != nil || deleted != 1 {
+		t.Fatalf("DeleteExpiredMFAChallenges() = %d, %v, want 1, nil", deleted, err)
+	}
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/gamaput/go-redeem/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type participantRepository struct {
+	DB *gorm.DB
+}
+
+// ParticipantRepository : represent the participant's repository contract
+type ParticipantRepository interface {
+	GetAllParticipants() ([]model.Participant, error)
+	GetParticipantByID(uint) (model.Participant, error)
+	ImportParticipants([]model.Participant) error
+}
+
+// NewParticipantRepository -> returns new participant repository
+func NewParticipantRepository(db *gorm.DB) ParticipantRepository {
+	return participantRepository{
+		DB: db,
+	}
+}
+
+func (pr participantRepository) GetAllParticipants() (participants []model.Participant, err error) {
+	return participants, pr.DB.Find(&participants).Error
+}
+
+func (pr participantRepository) GetParticipantByID(id uint) (participant model.Participant, err error) {
+	return participant, pr.DB.First(&participant, id).Error
+}
+
+// ImportParticipants menyimpan atau memperbarui banyak peserta berdasarkan NIK
+// dalam satu transaksi
+func (pr participantRepository) ImportParticipants(participants []model.Participant) error {
+	return pr.DB.Transaction(func(tx *gorm.DB) error {
+		for i := range participants {
+			if err := upsertParticipant(tx, &participants[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// upsertParticipant mencari peserta berdasarkan NIK di dalam transaksi tx dan
+// memperbarui data kontaknya, atau membuat peserta baru jika belum ada.
+// participant.ID diisi dengan ID peserta yang tersimpan.
+func upsertParticipant(tx *gorm.DB, participant *model.Participant) error {
+	details := map[string]interface{}{
+		"name":           participant.Name,
+		"city":           participant.City,
+		"address":        participant.Address,
+		"phone_no":       participant.PhoneNo,
+		"phone_operator": participant.PhoneOperator,
+	}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		var existing model.Participant
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("nik = ?", participant.NIK).First(&existing).Error
+		if err == nil {
+			participant.ID = existing.ID
+			return tx.Model(&existing).Updates(details).Error
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		// Peserta yang sama bisa dibuat bersamaan oleh transaksi lain;
+		// jika terjadi, baca ulang peserta yang sudah tersimpan
+		err = tx.Create(participant).Error
+		if err == nil || !isDuplicateKeyError(err) {
+			return err
+		}
+	}
+	return errors.New("failed to save participant")
+}
@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gamaput/go-redeem/model"
+	"github.com/gamaput/go-redeem/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrPartnerNotFound dikembalikan jika API key tidak dikenal atau partner tidak aktif
+	ErrPartnerNotFound = errors.New("partner not found")
+	// ErrPartnerNonceReused dikembalikan jika nonce sudah dipakai dalam jendela replay
+	ErrPartnerNonceReused = errors.New("request nonce has already been used")
+	// ErrPartnerDailyQuotaExceeded dikembalikan jika kuota harian partner sudah habis
+	ErrPartnerDailyQuotaExceeded = errors.New("partner daily quota exceeded")
+	// ErrPartnerTotalQuotaExceeded dikembalikan jika kuota total partner sudah habis
+	ErrPartnerTotalQuotaExceeded = errors.New("partner total quota exceeded")
+)
+
+type partnerRepository struct {
+	DB *gorm.DB
+}
+
+// PartnerRepository : represent the partner's repository contract
+type PartnerRepository interface {
+	CreatePartner(partner *model.Partner, apiKey string) error
+	GetAllPartners() ([]model.Partner, error)
+	GetPartnerByID(uint) (model.Partner, error)
+	GetPartnerByAPIKey(apiKey string) (model.Partner, error)
+	UpdatePartner(model.Partner) error
+	RotatePartnerCredentials(id uint, apiKey, secret string) error
+	UsePartnerNonce(partnerID uint, nonce string, now time.Time) error
+	DeleteExpiredPartnerNonces(before time.Time) (int64, error)
+	IssuePartnerCode(partner model.Partner, receiptNumber string) (model.PartnerIssuance, bool, error)
+}
+
+// NewPartnerRepository -> returns new partner repository
+func NewPartnerRepository(db *gorm.DB) PartnerRepository {
+	return partnerRepository{
+		DB: db,
+	}
+}
+
+func apiKeyHint(apiKey string) string {
+	if len(apiKey) > 8 {
+		return apiKey[:8]
+	}
+	return apiKey
+}
+
+func (pr partnerRepository) CreatePartner(partner *model.Partner, apiKey string) error {
+	// API key tidak disimpan dalam bentuk asli
+	partner.APIKeyHash = utils.HashToken(apiKey)
+	partner.APIKeyHint = apiKeyHint(apiKey)
+	return pr.DB.Create(partner).Error
+}
+
+func (pr partnerRepository) GetAllPartners() (partners []model.Partner, err error) {
+	return partners, pr.DB.Order("id").Find(&partners).Error
+}
+
+func (pr partnerRepository) GetPartnerByID(id uint) (partner model.Partner, err error) {
+	return partner, pr.DB.First(&partner, id).Error
+}
+
+// GetPartnerByAPIKey mengembalikan partner aktif pemilik apiKey
+func (pr partnerRepository) GetPartnerByAPIKey(apiKey string) (partner model.Partner, err error) {
+	err = pr.DB.Where("api_key_hash = ? AND active = ?", utils.HashToken(apiKey), true).First(&partner).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return partner, ErrPartnerNotFound
+	}
+	return partner, err
+}
+
+func (pr partnerRepository) UpdatePartner(partner model.Partner) error {
+	return pr.DB.Model(&model.Partner{}).Where("id = ?", partner.ID).Updates(map[string]interface{}{
+		"name":               partner.Name,
+		"campaign_id":        partner.CampaignID,
+		"active":             partner.Active,
+		"daily_quota":        partner.DailyQuota,
+		"total_quota":        partner.TotalQuota,
+		"max_uses":           partner.MaxUses,
+		"per_identity_limit": partner.PerIdentityLimit,
+	}).Error
+}
+
+// RotatePartnerCredentials mengganti API key dan secret partner; kredensial lama langsung tidak berlaku
+func (pr partnerRepository) RotatePartnerCredentials(id uint, apiKey, secret string) error {
+	return pr.DB.Model(&model.Partner{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"api_key_hash": utils.HashToken(apiKey),
+		"api_key_hint": apiKeyHint(apiKey),
+		"secret":       secret,
+	}).Error
+}
+
+// UsePartnerNonce mencatat nonce request partner; nonce yang masih tersimpan
+// ditolak. Timestamp request boleh berbeda hingga jendela replay ke depan
+// maupun ke belakang, sehingga pemanggil DeleteExpiredPartnerNonces harus
+// menyimpan nonce selama dua kali jendela replay.
+func (pr partnerRepository) UsePartnerNonce(partnerID uint, nonce string, now time.Time) error {
+	err := pr.DB.Create(&model.PartnerNonce{PartnerID: partnerID, Nonce: nonce, CreatedAt: now}).Error
+	if isDuplicateKeyError(err) {
+		return ErrPartnerNonceReused
+	}
+	return err
+}
+
+// DeleteExpiredPartnerNonces menghapus nonce yang dicatat sebelum before
+func (pr partnerRepository) DeleteExpiredPartnerNonces(before time.Time) (int64, error) {
+	result := pr.DB.Where("created_at < ?", before).Delete(&model.PartnerNonce{})
+	return result.RowsAffected, result.Error
+}
+
+// IssuePartnerCode menerbitkan kode redeem untuk nomor struk partner. Jika
+// nomor struk sudah pernah mendapat kode, kode yang sama dikembalikan dengan
+// created bernilai false dan kuota tidak dihitung lagi. Baris partner dikunci
+// selama transaksi agar kuota tidak terlampaui oleh request yang bersamaan.
+func (pr partnerRepository) IssuePartnerCode(partner model.Partner, receiptNumber string) (issuance model.PartnerIssuance, created bool, err error) {
+	err = pr.DB.Transaction(func(tx *gorm.DB) error {
+		var locked model.Partner
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&locked, partner.ID).Error; err != nil {
+			return err
+		}
+
+		err := tx.Where("partner_id = ? AND receipt_number = ?", locked.ID, receiptNumber).First(&issuance).Error
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		now := time.Now()
+		if locked.TotalQuota > 0 && locked.IssuedCount >= locked.TotalQuota {
+			return ErrPartnerTotalQuotaExceeded
+		}
+		if locked.DailyQuota > 0 {
+			var today int64
+			startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+			if err := tx.Model(&model.PartnerIssuance{}).Where("partner_id = ? AND created_at >= ?", locked.ID, startOfDay).Count(&today).Error; err != nil {
+				return err
+			}
+			if today >= int64(locked.DailyQuota) {
+				return ErrPartnerDailyQuotaExceeded
+			}
+		}
+
+		var campaign model.Campaign
+		if err := tx.First(&campaign, locked.CampaignID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrCampaignNotActive
+			}
+			return err
+		}
+		if !campaign.IsActive(now) {
+			return ErrCampaignNotActive
+		}
+
+		code, err := utils.GenerateUniqueCode(campaign.CodeSpec(), func(code string) (bool, error) {
+			var count int64
+			err := tx.Unscoped().Model(&model.RedeemCode{}).Where("code = ?", code).Count(&count).Error
+			return count > 0, err
+		})
+		if err != nil {
+			return err
+		}
+
+		// Kode partner berlaku selama kampanyenya berjalan, sama seperti kode satuan
+		redeemCode := model.RedeemCode{
+			CampaignID:       campaign.ID,
+			Code:             code,
+			ValidFrom:        &campaign.StartAt,
+			ValidUntil:       &campaign.EndAt,
+			Status:           model.CodeStatusActive,
+			MaxUses:          locked.MaxUses,
+			PerIdentityLimit: locked.PerIdentityLimit,
+		}
+		if err := tx.Create(&redeemCode).Error; err != nil {
+			return err
+		}
+
+		issuance = model.PartnerIssuance{
+			PartnerID:     locked.ID,
+			ReceiptNumber: receiptNumber,
+			RedeemCodeID:  redeemCode.ID,
+			Code:          code,
+		}
+		if err := tx.Create(&issuance).Error; err != nil {
+			return err
+		}
+		created = true
+		return tx.Model(&model.Partner{}).Where("id = ?", locked.ID).Update("issued_count", gorm.Expr("issued_count + 1")).Error
+	})
+	return issuance, created, err
+}
@@ -1,47 +1,125 @@
 package repository
 
 import (
+	"errors"
+	"fmt"
+	"time"
+
 	"github.com/gamaput/go-redeem/model"
+	"github.com/gamaput/go-redeem/notification"
+	"github.com/gamaput/go-redeem/utils"
 	"gorm.io/gorm"
 )
 
+var (
+	// ErrPrizeNotFound dikembalikan jika hadiah tidak ditemukan
+	ErrPrizeNotFound = errors.New("prize not found")
+	// ErrInsufficientStock dikembalikan jika perubahan stok membuat saldo menjadi negatif
+	ErrInsufficientStock = errors.New("insufficient prize stock")
+)
+
 type prizeRepository struct {
-	DB *gorm.DB
+	DB       *gorm.DB
+	Notifier notification.Notifier
 }
 
 type PrizeRepository interface {
-	GetRandomPrize() (model.Prize, error)
-	CreatePrize(prize *model.Prize) error
+	GetRandomPrize(campaign model.Campaign) (model.Prize, error)
+	CreatePrize(prize *model.Prize, actorID uint) error
 	GetAllPrizes() ([]model.Prize, error)
 	UpdatePrize(prize *model.Prize) error
 	DeletePrize(model.Prize) (model.Prize, error)
 	GetPrizeByID(uint) (model.Prize, error)
+	RecordInventory(entry *model.InventoryEntry) error
+	GetInventoryEntries(prizeID uint) ([]model.InventoryEntry, error)
 }
 
-func NewPrizeRepository(db *gorm.DB) PrizeRepository {
+func NewPrizeRepository(db *gorm.DB, notifier notification.Notifier) PrizeRepository {
 	return &prizeRepository{
-		DB: db,
+		DB:       db,
+		Notifier: notifier,
 	}
 }
 
-func (pr *prizeRepository) GetRandomPrize() (model.Prize, error) {
-	var prize model.Prize
-	if err := pr.DB.Model(&model.Prize{}).Scopes(prizeIsAvailable).Order("RAND()").First(&prize).Error; err != nil {
-		// Jika tidak ada hadiah yang tersedia, prize.ID akan diatur menjadi 0
-		if err == gorm.ErrRecordNotFound {
-			prize.ID = 0
-			return prize, nil
-		}
+// GetRandomPrize mengundi hadiah kampanye berdasarkan bobotnya tanpa mengurangi stok.
+// Jika hasil undian adalah "tidak dapat hadiah", prize.ID akan bernilai 0.
+func (pr *prizeRepository) GetRandomPrize(campaign model.Campaign) (model.Prize, error) {
+	prizes, err := availablePrizes(pr.DB, campaign.ID)
+	if err != nil {
 		return model.Prize{}, err
 	}
+	prize, _ := utils.PickWeightedPrize(drawRand, prizes, campaign.NoPrizeWeight)
 	return prize, nil
 }
 
-func (pr *prizeRepository) CreatePrize(prize *model.Prize) error {
-	if err := pr.DB.Create(&prize).Error; err != nil {
-		return err
+// drawRand adalah sumber acak untuk pengundian hadiah
+var drawRand = utils.NewRand(time.Now().UnixNano())
+
+func availablePrizes(db *gorm.DB, campaignID uint) (prizes []model.Prize, err error) {
+	return prizes, db.Model(&model.Prize{}).Where("campaign_id = ?", campaignID).Scopes(prizeIsAvailable).Order("id").Find(&prizes).Error
+}
+
+// takeRandomPrize mengundi hadiah milik campaign berdasarkan bobotnya dan mengurangi stoknya
+// di dalam transaksi tx. Pengurangan stok memakai update bersyarat
+// "quantity > 0" agar stok tidak pernah negatif walaupun ada transaksi lain
+// yang berjalan bersamaan; jika hadiah yang terpilih ternyata sudah habis,
+// hadiah itu dikeluarkan dan undian diulang dengan sisa hadiah.
+// Prize dengan ID 0 berarti "tidak dapat hadiah / coba lagi".
+func takeRandomPrize(tx *gorm.DB, campaign model.Campaign) (model.Prize, error) {
+	prizes, err := availablePrizes(tx, campaign.ID)
+	if err != nil {
+		return model.Prize{}, err
+	}
+
+	for {
+		prize, ok := utils.PickWeightedPrize(drawRand, prizes, campaign.NoPrizeWeight)
+		if !ok {
+			return model.Prize{}, nil
+		}
+
+		result := tx.Model(&model.Prize{}).Where("id = ?", prize.ID).Scopes(prizeIsAvailable).
+			Update("quantity", gorm.Expr("quantity - ?", 1))
+		if result.Error != nil {
+			return model.Prize{}, result.Error
+		}
+		if result.RowsAffected == 1 {
+			prize.Quantity--
+			return prize, nil
+		}
+
+		for i := range prizes {
+			if prizes[i].ID == prize.ID {
+				prizes[i].Quantity = 0
+			}
+		}
 	}
-	return nil
+}
+
+// CreatePrize menyimpan hadiah baru; stok awalnya dicatat sebagai entri restock
+func (pr *prizeRepository) CreatePrize(prize *model.Prize, actorID uint) error {
+	return pr.DB.Transaction(func(tx *gorm.DB) error {
+		quantity := prize.Quantity
+		prize.Quantity = 0
+		if err := tx.Create(prize).Error; err != nil {
+			return err
+		}
+		if quantity == 0 {
+			return nil
+		}
+
+		entry := model.InventoryEntry{
+			PrizeID: prize.ID,
+			Type:    model.InventoryRestock,
+			Delta:   quantity,
+			Reason:  "initial stock",
+			ActorID: actorID,
+		}
+		if _, err := applyInventoryEntry(tx, &entry); err != nil {
+			return err
+		}
+		prize.Quantity = entry.Balance
+		return nil
+	})
 }
 
 func (pr *prizeRepository) GetAllPrizes() ([]model.Prize, error) {
@@ -54,13 +132,16 @@ func (pr *prizeRepository) GetAllPrizes() ([]model.Prize, error) {
 }
 
 func prizeIsAvailable(db *gorm.DB) *gorm.DB {
-	return db.Where("quantity > 0")
+	return db.Where("quantity > 0 AND weight > 0")
 }
 
+// UpdatePrize memperbarui nama dan bobot hadiah. Stok hanya bisa diubah
+// lewat RecordInventory.
 func (r *prizeRepository) UpdatePrize(prize *model.Prize) error {
 	if err := r.DB.Model(&model.Prize{}).Where("id =?", prize.ID).Updates(map[string]interface{}{
-		"name":     prize.Name,
-		"quantity": prize.Quantity, // Menggunakan nilai kuantitas yang baru
+		"name":                prize.Name,
+		"weight":              prize.Weight,
+		"low_stock_threshold": prize.LowStockThreshold,
 	}).Error; err != nil {
 		return err
 	}
@@ -78,3 +159,110 @@ func (r *prizeRepository) DeletePrize(prize model.Prize) (model.Prize, error) {
 func (r *prizeRepository) GetPrizeByID(id uint) (prize model.Prize, err error) {
 	return prize, r.DB.First(&prize, id).Error
 }
+
+// RecordInventory mencatat entri buku stok dan memperbarui saldo hadiah
+// dalam satu transaksi. entry.Balance diisi dengan saldo setelah entri.
+func (r *prizeRepository) RecordInventory(entry *model.InventoryEntry) error {
+	if err := model.ValidateInventoryDelta(entry.Type, entry.Delta); err != nil {
+		return err
+	}
+	var alert *notification.Event
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		prize, err := applyInventoryEntry(tx, entry)
+		if err != nil {
+			return err
+		}
+		alert, err = stockLevelAlert(tx, prize, *entry)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if alert != nil {
+		r.Notifier.Notify(*alert)
+	}
+	return nil
+}
+
+// GetInventoryEntries mengembalikan riwayat pergerakan stok hadiah, dari yang terlama
+func (r *prizeRepository) GetInventoryEntries(prizeID uint) (entries []model.InventoryEntry, err error) {
+	return entries, r.DB.Where("prize_id = ?", prizeID).Order("id").Find(&entries).Error
+}
+
+// applyInventoryEntry mengubah saldo hadiah sebesar entry.Delta di dalam
+// transaksi tx lalu mencatat entrinya. Update bersyarat menjaga saldo tidak
+// pernah negatif walaupun ada transaksi lain yang berjalan bersamaan.
+func applyInventoryEntry(tx *gorm.DB, entry *model.InventoryEntry) (model.Prize, error) {
+	result := tx.Model(&model.Prize{}).Where("id = ? AND quantity + ? >= 0", entry.PrizeID, entry.Delta).
+		Update("quantity", gorm.Expr("quantity + ?", entry.Delta))
+	if result.Error != nil {
+		return model.Prize{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		var count int64
+		if err := tx.Model(&model.Prize{}).Where("id = ?", entry.PrizeID).Count(&count).Error; err != nil {
+			return model.Prize{}, err
+		}
+		if count == 0 {
+			return model.Prize{}, ErrPrizeNotFound
+		}
+		return model.Prize{}, ErrInsufficientStock
+	}
+	return logInventoryEntry(tx, entry)
+}
+
+// logInventoryEntry mencatat entri untuk perubahan saldo yang sudah dilakukan
+// di dalam transaksi tx. Baris hadiah sudah terkunci oleh update tersebut,
+// sehingga saldo yang dibaca adalah saldo setelah perubahan.
+func logInventoryEntry(tx *gorm.DB, entry *model.InventoryEntry) (prize model.Prize, err error) {
+	if err := tx.First(&prize, entry.PrizeID).Error; err != nil {
+		return prize, err
+	}
+	entry.Balance = prize.Quantity
+	return prize, tx.Create(entry).Error
+}
+
+// stockLevelAlert mencatat event prize.stock_low di outbox jika entry membuat
+// stok hadiah turun melewati LowStockThreshold atau habis, dan mengembalikan
+// peringatan yang harus dikirim ke notifier setelah transaksi tx di-commit.
+// Peringatan hanya dibuat saat batas dilewati, bukan pada setiap penukaran
+// setelahnya.
+func stockLevelAlert(tx *gorm.DB, prize model.Prize, entry model.InventoryEntry) (*notification.Event, error) {
+	if entry.Delta >= 0 {
+		return nil, nil
+	}
+	before := entry.Balance - entry.Delta
+	soldOut := entry.Balance == 0
+	if !soldOut && (entry.Balance > prize.LowStockThreshold || before <= prize.LowStockThreshold) {
+		return nil, nil
+	}
+
+	data := map[string]interface{}{
+		"prize_id":    prize.ID,
+		"campaign_id": prize.CampaignID,
+		"name":        prize.Name,
+		"quantity":    entry.Balance,
+		"threshold":   prize.LowStockThreshold,
+		"sold_out":    soldOut,
+	}
+	if err := enqueueOutboxEvent(tx, model.EventPrizeStockLow, data); err != nil {
+		return nil, err
+	}
+
+	if soldOut {
+		return &notification.Event{
+			Type:    notification.EventPrizeSoldOut,
+			Key:     fmt.Sprintf("%s:%d", notification.EventPrizeSoldOut, prize.ID),
+			Subject: fmt.Sprintf("Prize sold out: %s", prize.Name),
+			Message: fmt.Sprintf("Prize %q (ID %d, campaign %d) is out of stock and will no longer be drawn.", prize.Name, prize.ID, prize.CampaignID),
+			Data:    data,
+		}, nil
+	}
+	return &notification.Event{
+		Type:    notification.EventPrizeStockLow,
+		Key:     fmt.Sprintf("%s:%d", notification.EventPrizeStockLow, prize.ID),
+		Subject: fmt.Sprintf("Prize stock low: %s", prize.Name),
+		Message: fmt.Sprintf("Prize %q (ID %d, campaign %d) has %d left, at or below the threshold of %d.", prize.Name, prize.ID, prize.CampaignID, entry.Balance, prize.LowStockThreshold),
+		Data:    data,
+	}, nil
+}
@@ -1,28 +1,60 @@
 package repository
 
 import (
+	"errors"
+	"time"
+
 	"github.com/gamaput/go-redeem/model"
+	"github.com/gamaput/go-redeem/notification"
+	"github.com/gamaput/go-redeem/utils"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrRedeemCodeNotFound dikembalikan jika kode redeem tidak ada
+	ErrRedeemCodeNotFound = errors.New("redeem code not found")
+	// ErrRedeemCodeAlreadyRedeemed dikembalikan jika kode redeem sudah dipakai
+	ErrRedeemCodeAlreadyRedeemed = errors.New("redeem code has already been redeemed")
+	// ErrCampaignNotActive dikembalikan jika kampanye pemilik kode tidak sedang aktif
+	ErrCampaignNotActive = errors.New("campaign is not active")
+	// ErrRedeemCodeNotYetValid dikembalikan jika masa berlaku kode belum dimulai
+	ErrRedeemCodeNotYetValid = errors.New("redeem code is not valid yet")
+	// ErrRedeemCodeExpired dikembalikan jika masa berlaku kode sudah lewat
+	ErrRedeemCodeExpired = errors.New("redeem code has expired")
+	// ErrRedeemCodeRevoked dikembalikan jika kode sudah dicabut oleh admin
+	ErrRedeemCodeRevoked = errors.New("redeem code has been revoked")
+	// ErrRedemptionLimitReached dikembalikan jika NoKTP sudah mencapai batas penukaran kode ini
+	ErrRedemptionLimitReached = errors.New("redemption limit per participant reached")
+	// ErrRedeemCodeNotRevocable dikembalikan jika status kode tidak mengizinkan aksi pencabutan
+	ErrRedeemCodeNotRevocable = errors.New("redeem code status does not allow this action")
 )
 
 type redeemCodeRepository struct {
-	DB *gorm.DB
+	DB       *gorm.DB
+	Notifier notification.Notifier
 }
 
 // RedeemCodeRepository represents the redeem code repository contract
 type RedeemCodeRepository interface {
 	SaveRedeemCode(redeemCode *model.RedeemCode) error
 	GetRedeemCodeByCode(code string) (*model.RedeemCode, error)
-	UpdateRedeemCode(redeemCode *model.RedeemCode) error
+	CodeExists(code string) (bool, error)
 	RedeemCode(redeemCode *model.RedeemCode) error
+	Redeem(code string, redemption *model.Redemption) (model.Prize, error)
+	RevokeCode(code string, reason string, actorID uint) error
+	UnrevokeCode(code string, reason string, actorID uint) error
+	RevokeBatch(batchID uint, reason string, actorID uint) (int64, error)
+	UnrevokeBatch(batchID uint, reason string, actorID uint) (int64, error)
 	CreateRedeemCode(redeemCode *model.RedeemCode) error
 	GetAllRedeems() (redeems []model.RedeemCode, err error)
 }
 
 // NewRedeemCodeRepository returns a new instance of RedeemCodeRepository
-func NewRedeemCodeRepository(db *gorm.DB) RedeemCodeRepository {
+func NewRedeemCodeRepository(db *gorm.DB, notifier notification.Notifier) RedeemCodeRepository {
 	return &redeemCodeRepository{
-		DB: db,
+		DB:       db,
+		Notifier: notifier,
 	}
 }
 
@@ -49,31 +81,298 @@ func (r *redeemCodeRepository) GetRedeemCodeByCode(code string) (*model.RedeemCo
 	return &redeemCode, nil
 }
 
-func (r *redeemCodeRepository) UpdateRedeemCode(redeemCode *model.RedeemCode) error {
-	result := r.DB.Model(&model.RedeemCode{}).Where("id = ?", redeemCode.ID).Updates(map[string]interface{}{
-		"is_redeemed": redeemCode.IsRedeemed,
-		"name":        redeemCode.Name,
-		"no_ktp":      redeemCode.NoKTP,
-		"city":        redeemCode.City,
-		"address":     redeemCode.Address,
-		"phone_no":    redeemCode.PhoneNo,
-		"prize_id":    redeemCode.PrizeID,
-	})
-
-	if result.Error != nil {
-		return result.Error
+// CodeExists memeriksa apakah kode sudah pernah dibuat, termasuk kode yang sudah dihapus
+func (r *redeemCodeRepository) CodeExists(code string) (bool, error) {
+	var count int64
+	if err := r.DB.Unscoped().Model(&model.RedeemCode{}).Where("code = ?", code).Count(&count).Error; err != nil {
+		return false, err
 	}
-
-	return nil
+	return count > 0, nil
 }
 
 func (r *redeemCodeRepository) RedeemCode(redeemCode *model.RedeemCode) error {
-	if err := r.DB.Model(&model.RedeemCode{}).Where("id = ?", redeemCode.ID).Update("is_redeemed", true).Error; err != nil {
+	if err := r.DB.Model(&model.RedeemCode{}).Where("id = ?", redeemCode.ID).Update("status", model.CodeStatusRedeemed).Error; err != nil {
 		return err
 	}
 	return nil
 }
 
+// Redeem menukarkan kode, menyimpan atau memperbarui Participant berdasarkan
+// NIK dan menyimpan redemption dalam satu transaksi. Baris kode dikunci (SELECT ... FOR UPDATE) dan stok hadiah
+// dikurangi dengan update bersyarat, sehingga request paralel tidak bisa
+// melebihi MaxUses atau PerIdentityLimit kode ataupun membuat stok hadiah
+// menjadi negatif.
+// Kode harus berada dalam masa berlakunya, dan hadiah hanya diundi dari
+// kampanye pemilik kode yang sedang aktif.
+// Prize yang dikembalikan memiliki ID 0 jika tidak ada hadiah yang tersedia.
+func (r *redeemCodeRepository) Redeem(code string, redemption *model.Redemption) (model.Prize, error) {
+	var prize model.Prize
+	var alert *notification.Event
+	var expired bool
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		var existing model.RedeemCode
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("code = ?", code).First(&existing).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrRedeemCodeNotFound
+			}
+			return err
+		}
+		switch existing.Status {
+		case model.CodeStatusRedeemed:
+			return ErrRedeemCodeAlreadyRedeemed
+		case model.CodeStatusRevoked:
+			return ErrRedeemCodeRevoked
+		case model.CodeStatusExpired:
+			return ErrRedeemCodeExpired
+		}
+
+		now := time.Now()
+		if existing.ValidFrom != nil && now.Before(*existing.ValidFrom) {
+			return ErrRedeemCodeNotYetValid
+		}
+		if existing.ValidUntil != nil && !now.Before(*existing.ValidUntil) {
+			// Status expired disimpan, sehingga transaksi tetap di-commit
+			expired = true
+			return tx.Model(&model.RedeemCode{}).Where("id = ?", existing.ID).Update("status", model.CodeStatusExpired).Error
+		}
+
+		// Hanya kode dari kampanye yang sedang aktif yang bisa ditukarkan
+		var campaign model.Campaign
+		if err := tx.First(&campaign, existing.CampaignID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrCampaignNotActive
+			}
+			return err
+		}
+		if !campaign.IsActive(now) {
+			return ErrCampaignNotActive
+		}
+
+		if existing.UseCount >= existing.MaxUses {
+			return ErrRedeemCodeAlreadyRedeemed
+		}
+
+		var identityUses int64
+		if err := tx.Model(&model.Redemption{}).Where("redeem_code_id = ? AND no_ktp = ?", existing.ID, redemption.NoKTP).Count(&identityUses).Error; err != nil {
+			return err
+		}
+		if identityUses >= int64(existing.PerIdentityLimit) {
+			return ErrRedemptionLimitReached
+		}
+
+		var err error
+		prize, err = takeRandomPrize(tx, campaign)
+		if err != nil {
+			return err
+		}
+
+		participant := model.Participant{
+			NIK:           redemption.NoKTP,
+			Name:          redemption.Name,
+			City:          redemption.City,
+			Address:       redemption.Address,
+			PhoneNo:       redemption.PhoneNo,
+			PhoneOperator: redemption.PhoneOperator,
+		}
+		if err := upsertParticipant(tx, &participant); err != nil {
+			return err
+		}
+
+		redemption.RedeemCodeID = existing.ID
+		redemption.ParticipantID = participant.ID
+		redemption.PrizeID = prize.ID
+		if prize.ID != 0 {
+			redemption.FulfillmentStatus = model.FulfillmentPendingVerification
+		}
+		if err := tx.Create(redemption).Error; err != nil {
+			return err
+		}
+		if prize.ID != 0 {
+			// Stok sudah dikurangi oleh takeRandomPrize
+			award := model.InventoryEntry{
+				PrizeID:      prize.ID,
+				Type:         model.InventoryAward,
+				Delta:        -1,
+				Reason:       "redemption",
+				RedemptionID: redemption.ID,
+			}
+			if prize, err = logInventoryEntry(tx, &award); err != nil {
+				return err
+			}
+			if alert, err = stockLevelAlert(tx, prize, award); err != nil {
+				return err
+			}
+		}
+
+		// Nama dan nomor telepon dikirim tersamar; pelanggan webhook yang
+		// membutuhkan data lengkap memakai participant_id
+		if err := enqueueOutboxEvent(tx, model.EventRedemptionCreated, map[string]interface{}{
+			"redemption_id":  redemption.ID,
+			"redeem_code_id": existing.ID,
+			"code":           existing.Code,
+			"campaign_id":    existing.CampaignID,
+			"participant_id": participant.ID,
+			"name":           utils.MaskName(redemption.Name),
+			"phone_no":       utils.MaskPhone(redemption.PhoneNo),
+			"city":           redemption.City,
+			"prize_id":       prize.ID,
+			"prize_name":     prize.Name,
+			"redeemed_at":    redemption.CreatedAt,
+		}); err != nil {
+			return err
+		}
+
+		// Kode berstatus redeemed setelah mencapai MaxUses
+		status := model.CodeStatusActive
+		if existing.UseCount+1 >= existing.MaxUses {
+			status = model.CodeStatusRedeemed
+		}
+		result := tx.Model(&model.RedeemCode{}).
+			Where("id = ? AND status = ? AND use_count = ?", existing.ID, model.CodeStatusActive, existing.UseCount).
+			Updates(map[string]interface{}{
+				"status":    status,
+				"use_count": existing.UseCount + 1,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrRedeemCodeAlreadyRedeemed
+		}
+		return nil
+	})
+	if err != nil {
+		return model.Prize{}, err
+	}
+	if expired {
+		return model.Prize{}, ErrRedeemCodeExpired
+	}
+	if alert != nil {
+		r.Notifier.Notify(*alert)
+	}
+	return prize, nil
+}
+
+// RevokeCode mencabut satu kode yang belum ditukarkan beserta alasannya
+func (r *redeemCodeRepository) RevokeCode(code string, reason string, actorID uint) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		var existing model.RedeemCode
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("code = ?", code).First(&existing).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrRedeemCodeNotFound
+			}
+			return err
+		}
+		if existing.Status != model.CodeStatusActive && existing.Status != model.CodeStatusExpired {
+			return ErrRedeemCodeNotRevocable
+		}
+
+		if err := tx.Model(&model.RedeemCode{}).Where("id = ?", existing.ID).Updates(revokeColumns(reason, actorID)).Error; err != nil {
+			return err
+		}
+		return tx.Create(&model.CodeRevocation{
+			RedeemCodeID: existing.ID,
+			Action:       model.RevocationActionRevoke,
+			Reason:       reason,
+			ActorID:      actorID,
+			Affected:     1,
+		}).Error
+	})
+}
+
+// UnrevokeCode mengaktifkan kembali kode yang dicabut karena kesalahan
+func (r *redeemCodeRepository) UnrevokeCode(code string, reason string, actorID uint) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		var existing model.RedeemCode
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("code = ?", code).First(&existing).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrRedeemCodeNotFound
+			}
+			return err
+		}
+		if existing.Status != model.CodeStatusRevoked {
+			return ErrRedeemCodeNotRevocable
+		}
+
+		if err := tx.Model(&model.RedeemCode{}).Where("id = ?", existing.ID).Updates(unrevokeColumns()).Error; err != nil {
+			return err
+		}
+		return tx.Create(&model.CodeRevocation{
+			RedeemCodeID: existing.ID,
+			Action:       model.RevocationActionUnrevoke,
+			Reason:       reason,
+			ActorID:      actorID,
+			Affected:     1,
+		}).Error
+	})
+}
+
+// RevokeBatch mencabut semua kode dalam batch yang belum ditukarkan dan
+// mengembalikan jumlah kode yang dicabut
+func (r *redeemCodeRepository) RevokeBatch(batchID uint, reason string, actorID uint) (int64, error) {
+	var affected int64
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.RedeemCode{}).
+			Where("batch_id = ? AND status IN ?", batchID, []string{model.CodeStatusActive, model.CodeStatusExpired}).
+			Updates(revokeColumns(reason, actorID))
+		if result.Error != nil {
+			return result.Error
+		}
+		affected = result.RowsAffected
+		return tx.Create(&model.CodeRevocation{
+			BatchID:  batchID,
+			Action:   model.RevocationActionRevoke,
+			Reason:   reason,
+			ActorID:  actorID,
+			Affected: affected,
+		}).Error
+	})
+	return affected, err
+}
+
+// UnrevokeBatch mengaktifkan kembali semua kode yang dicabut dalam batch dan
+// mengembalikan jumlah kode yang diaktifkan
+func (r *redeemCodeRepository) UnrevokeBatch(batchID uint, reason string, actorID uint) (int64, error) {
+	var affected int64
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.RedeemCode{}).
+			Where("batch_id = ? AND status = ?", batchID, model.CodeStatusRevoked).
+			Updates(unrevokeColumns())
+		if result.Error != nil {
+			return result.Error
+		}
+		affected = result.RowsAffected
+		return tx.Create(&model.CodeRevocation{
+			BatchID:  batchID,
+			Action:   model.RevocationActionUnrevoke,
+			Reason:   reason,
+			ActorID:  actorID,
+			Affected: affected,
+		}).Error
+	})
+	return affected, err
+}
+
+func revokeColumns(reason string, actorID uint) map[string]interface{} {
+	return map[string]interface{}{
+		"status":         model.CodeStatusRevoked,
+		"revoked_reason": reason,
+		"revoked_by":     actorID,
+		"revoked_at":     time.Now(),
+	}
+}
+
+// unrevokeColumns mengembalikan kode ke status active; kode yang sudah lewat
+// masa berlakunya akan ditandai expired kembali saat ditukarkan
+func unrevokeColumns() map[string]interface{} {
+	return map[string]interface{}{
+		"status":         model.CodeStatusActive,
+		"revoked_reason": "",
+		"revoked_by":     0,
+		"revoked_at":     nil,
+	}
+}
+
 func (r *redeemCodeRepository) GetAllRedeems() (redeems []model.RedeemCode, err error) {
 	return redeems, r.DB.Find(&redeems).Error
 }
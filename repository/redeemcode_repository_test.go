@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gamaput/go-redeem/model"
+	"github.com/gamaput/go-redeem/notification"
+)
+
+// TestRedeemConcurrent menebus satu kode dari banyak goroutine sekaligus.
+// Penguncian baris (SELECT ... FOR UPDATE) dan update use_count bersyarat
+// hanya benar-benar diuji secara bersamaan jika TEST_MYSQL_DSN diisi. Tanpa
+// itu SQLite (_txlock=immediate) menjalankan transaksi satu per satu, sehingga
+// test ini hanya memeriksa invariannya secara berurutan.
+func TestRedeemConcurrent(t *testing.T) {
+	if os.Getenv("TEST_MYSQL_DSN") == "" {
+		t.Log("TEST_MYSQL_DSN is not set: transactions run serialised on SQLite, only the invariants are checked")
+	}
+	tests := []struct {
+		name       string
+		goroutines int
+		maxUses    int
+		quantity   int
+	}{
+		{name: "single use code", goroutines: 200, maxUses: 1, quantity: 5},
+		{name: "more requests than uses", goroutines: 300, maxUses: 50, quantity: 30},
+		{name: "more uses than requests", goroutines: 100, maxUses: 500, quantity: 150},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			now := time.Now()
+			campaign := model.Campaign{Name: "test", Status: model.CampaignStatusActive, StartAt: now.Add(-time.Hour), EndAt: now.Add(time.Hour)}
+			if err := db.Create(&campaign).Error; err != nil {
+				t.Fatal(err)
+			}
+			code := model.RedeemCode{CampaignID: campaign.ID, Code: "CONCURRENT", MaxUses: tt.maxUses, PerIdentityLimit: 1}
+			if err := db.Create(&code).Error; err != nil {
+				t.Fatal(err)
+			}
+			prize := model.Prize{CampaignID: campaign.ID, Name: "prize", Quantity: tt.quantity, Weight: 1}
+			if err := NewPrizeRepository(db, notification.Nop{}).CreatePrize(&prize, 0); err != nil {
+				t.Fatal(err)
+			}
+
+			repo := NewRedeemCodeRepository(db, notification.Nop{})
+			var wg sync.WaitGroup
+			var mu sync.Mutex
+			var succeeded, won int
+			for i := 0; i < tt.goroutines; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					redemption := model.Redemption{Name: "participant", NoKTP: fmt.Sprintf("3171%012d", i)}
+					got, err := repo.Redeem(code.Code, &redemption)
+					mu.Lock()
+					defer mu.Unlock()
+					switch {
+					case err == nil:
+						succeeded++
+						if got.ID != 0 {
+							won++
+						}
+					case !errors.Is(err, ErrRedeemCodeAlreadyRedeemed):
+						t.Errorf("Redeem: unexpected error %v", err)
+					}
+				}(i)
+			}
+			wg.Wait()
+
+			wantSucceeded := minInt(tt.goroutines, tt.maxUses)
+			if succeeded != wantSucceeded {
+				t.Errorf("succeeded = %d, want %d", succeeded, wantSucceeded)
+			}
+			if wantWon := minInt(wantSucceeded, tt.quantity); won != wantWon {
+				t.Errorf("won = %d, want %d", won, wantWon)
+			}
+
+			var redemptions int64
+			db.Model(&model.Redemption{}).Where("redeem_code_id = ?", code.ID).Count(&redemptions)
+			if int(redemptions) != wantSucceeded {
+				t.Errorf("stored redemptions = %d, want %d", redemptions, wantSucceeded)
+			}
+			var stored model.RedeemCode
+			db.First(&stored, code.ID)
+			if stored.UseCount != wantSucceeded {
+				t.Errorf("use_count = %d, want %d", stored.UseCount, wantSucceeded)
+			}
+
+			var final model.Prize
+			db.First(&final, prize.ID)
+			if final.Quantity < 0 {
+				t.Errorf("quantity = %d, must not be negative", final.Quantity)
+			}
+			if final.Quantity != tt.quantity-won {
+				t.Errorf("quantity = %d, want %d", final.Quantity, tt.quantity-won)
+			}
+			var ledger struct{ Sum int }
+			db.Model(&model.InventoryEntry{}).Select("COALESCE(SUM(delta), 0) AS sum").Where("prize_id = ?", prize.ID).Scan(&ledger)
+			if ledger.Sum != final.Quantity {
+				t.Errorf("ledger sum = %d, want quantity %d", ledger.Sum, final.Quantity)
+			}
+		})
+	}
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gamaput/go-redeem/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrRedemptionNotFound dikembalikan jika redemption tidak ditemukan
+	ErrRedemptionNotFound = errors.New("redemption not found")
+	// ErrFulfillmentNotApplicable dikembalikan untuk redemption tanpa hadiah
+	ErrFulfillmentNotApplicable = errors.New("redemption has no prize to fulfill")
+	// ErrInvalidFulfillmentTransition dikembalikan jika perpindahan status tidak diizinkan
+	ErrInvalidFulfillmentTransition = errors.New("invalid fulfillment transition")
+	// ErrShipmentDetailsRequired dikembalikan jika status shipped tidak disertai kurir dan nomor resi
+	ErrShipmentDetailsRequired = errors.New("courier and tracking number are required to ship a prize")
+)
+
+// RedemptionFilter membatasi redemption yang dikembalikan; field bernilai kosong diabaikan
+type RedemptionFilter struct {
+	RedeemCodeID      uint
+	ParticipantID     uint
+	FulfillmentStatus string
+	CityMismatch      bool // hanya redemption yang kotanya tidak sesuai dengan NIK
+}
+
+type redemptionRepository struct {
+	DB *gorm.DB
+}
+
+// RedemptionRepository : represent the redemption's repository contract
+type RedemptionRepository interface {
+	GetAllRedemptions(RedemptionFilter) ([]model.Redemption, error)
+	GetRedemptionByID(uint) (model.Redemption, error)
+	GetRedemptionsByCode(code string) ([]model.Redemption, error)
+	TransitionFulfillment(id uint, transition model.FulfillmentTransition) (model.Redemption, error)
+	GetFulfillmentHistory(id uint) ([]model.FulfillmentTransition, error)
+}
+
+// NewRedemptionRepository -> returns new redemption repository
+func NewRedemptionRepository(db *gorm.DB) RedemptionRepository {
+	return redemptionRepository{
+		DB: db,
+	}
+}
+
+func (rr redemptionRepository) GetAllRedemptions(filter RedemptionFilter) (redemptions []model.Redemption, err error) {
+	query := rr.DB.Order("id")
+	if filter.RedeemCodeID != 0 {
+		query = query.Where("redeem_code_id = ?", filter.RedeemCodeID)
+	}
+	if filter.ParticipantID != 0 {
+		query = query.Where("participant_id = ?", filter.ParticipantID)
+	}
+	if filter.FulfillmentStatus != "" {
+		query = query.Where("fulfillment_status = ?", filter.FulfillmentStatus)
+	}
+	if filter.CityMismatch {
+		query = query.Where("city_mismatch = ?", true)
+	}
+	return redemptions, query.Find(&redemptions).Error
+}
+
+func (rr redemptionRepository) GetRedemptionByID(id uint) (redemption model.Redemption, err error) {
+	return redemption, rr.DB.First(&redemption, id).Error
+}
+
+// GetRedemptionsByCode mengembalikan semua redemption dari kode redeem dalam bentuk kanonik
+func (rr redemptionRepository) GetRedemptionsByCode(code string) (redemptions []model.Redemption, err error) {
+	return redemptions, rr.DB.
+		Joins("JOIN redeem_codes ON redeem_codes.id = redemptions.redeem_code_id").
+		Where("redeem_codes.code = ?", code).
+		Order("redemptions.id").
+		Find(&redemptions).Error
+}
+
+// TransitionFulfillment memindahkan status pemenuhan redemption ke
+// transition.ToStatus dan mencatatnya di riwayat dalam satu transaksi.
+// Kurir dan nomor resi wajib diisi saat status menjadi shipped.
+func (rr redemptionRepository) TransitionFulfillment(id uint, transition model.FulfillmentTransition) (redemption model.Redemption, err error) {
+	err = rr.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&redemption, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrRedemptionNotFound
+			}
+			return err
+		}
+		if redemption.PrizeID == 0 {
+			return ErrFulfillmentNotApplicable
+		}
+		if !model.CanTransitionFulfillment(redemption.FulfillmentStatus, transition.ToStatus) {
+			return fmt.Errorf("%w from %s to %s", ErrInvalidFulfillmentTransition, redemption.FulfillmentStatus, transition.ToStatus)
+		}
+
+		updates := map[string]interface{}{"fulfillment_status": transition.ToStatus}
+		if transition.ToStatus == model.FulfillmentShipped {
+			if transition.Courier == "" || transition.TrackingNumber == "" {
+				return ErrShipmentDetailsRequired
+			}
+			updates["courier"] = transition.Courier
+			updates["tracking_number"] = transition.TrackingNumber
+		}
+		transition.RedemptionID = redemption.ID
+		transition.FromStatus = redemption.FulfillmentStatus
+		if transition.ToStatus != model.FulfillmentShipped {
+			transition.Courier = ""
+			transition.TrackingNumber = ""
+		}
+		if err := tx.Model(&model.Redemption{}).Where("id = ?", redemption.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		if err := enqueueOutboxEvent(tx, model.EventRedemptionFulfillmentUpdated, map[string]interface{}{
+			"redemption_id":   redemption.ID,
+			"redeem_code_id":  redemption.RedeemCodeID,
+			"prize_id":        redemption.PrizeID,
+			"from_status":     transition.FromStatus,
+			"to_status":       transition.ToStatus,
+			"courier":         transition.Courier,
+			"tracking_number": transition.TrackingNumber,
+		}); err != nil {
+			return err
+		}
+
+		// Hadiah dari penukaran yang ditolak dikembalikan ke stok
+		if transition.ToStatus == model.FulfillmentRejected {
+			if _, err := applyInventoryEntry(tx, &model.InventoryEntry{
+				PrizeID:      redemption.PrizeID,
+				Type:         model.InventoryReturn,
+				Delta:        1,
+				Reason:       "fulfillment rejected",
+				ActorID:      transition.ActorID,
+				RedemptionID: redemption.ID,
+			}); err != nil && !errors.Is(err, ErrPrizeNotFound) {
+				return err
+			}
+		}
+		return tx.Create(&transition).Error
+	})
+	if err != nil {
+		return redemption, err
+	}
+	return redemption, rr.DB.First(&redemption, id).Error
+}
+
+// GetFulfillmentHistory mengembalikan riwayat perpindahan status pemenuhan redemption, dari yang terlama
+func (rr redemptionRepository) GetFulfillmentHistory(id uint) (transitions []model.FulfillmentTransition, err error) {
+	return transitions, rr.DB.Where("redemption_id = ?", id).Order("id").Find(&transitions).Error
+}
@@ -0,0 +1,189 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gamaput/go-redeem/model"
+	"github.com/gamaput/go-redeem/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrRefreshTokenInvalid dikembalikan jika refresh token tidak dikenal atau kedaluwarsa
+	ErrRefreshTokenInvalid = errors.New("invalid refresh token")
+	// ErrRefreshTokenReused dikembalikan jika refresh token yang sudah dirotasi dipakai lagi;
+	// seluruh sesinya sudah dicabut
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected, session revoked")
+	// ErrSessionNotFound dikembalikan jika sesi tidak ada, bukan milik user, atau sudah tidak aktif
+	ErrSessionNotFound = errors.New("session not found")
+)
+
+// Alasan pencabutan sesi
+const (
+	SessionRevokedLogout = "logout"
+	SessionRevokedReuse  = "refresh token reuse"
+	SessionRevokedUser   = "revoked by user"
+	SessionRevokedAdmin  = "revoked by admin"
+	SessionRevokedReset  = "password reset"
+	// SessionRevokedPasswordChange dipakai saat password diubah lewat UpdateUser
+	SessionRevokedPasswordChange = "password changed"
+)
+
+type sessionRepository struct {
+	DB *gorm.DB
+}
+
+// SessionRepository : represent the session's repository contract
+type SessionRepository interface {
+	CreateSession(session *model.Session, refreshToken string) error
+	RotateRefreshToken(refreshToken, newRefreshToken string, ttl time.Duration) (model.Session, error)
+	IsSessionActive(sessionID, userID uint) (bool, error)
+	GetActiveSessions(userID uint) ([]model.Session, error)
+	RevokeSession(userID, sessionID uint, reason string) error
+	RevokeSessionByRefreshToken(refreshToken, reason string) error
+	RevokeAllSessions(userID uint, reason string) (int64, error)
+	RevokeOtherSessions(userID, keepSessionID uint, reason string) (int64, error)
+}
+
+// NewSessionRepository -> returns new session repository
+func NewSessionRepository(db *gorm.DB) SessionRepository {
+	return sessionRepository{
+		DB: db,
+	}
+}
+
+// CreateSession menyimpan sesi baru beserta refresh token pertamanya
+func (sr sessionRepository) CreateSession(session *model.Session, refreshToken string) error {
+	return sr.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(session).Error; err != nil {
+			return err
+		}
+		return tx.Create(&model.RefreshToken{
+			SessionID: session.ID,
+			TokenHash: utils.HashToken(refreshToken),
+			ExpiresAt: session.ExpiresAt,
+		}).Error
+	})
+}
+
+// RotateRefreshToken menukar refreshToken dengan newRefreshToken dan
+// memperpanjang sesi selama ttl. Jika refreshToken sudah pernah dirotasi,
+// sesinya dicabut dan ErrRefreshTokenReused dikembalikan.
+func (sr sessionRepository) RotateRefreshToken(refreshToken, newRefreshToken string, ttl time.Duration) (session model.Session, err error) {
+	var reused bool
+	err = sr.DB.Transaction(func(tx *gorm.DB) error {
+		var token model.RefreshToken
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("token_hash = ?", utils.HashToken(refreshToken)).First(&token).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrRefreshTokenInvalid
+			}
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&session, token.SessionID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrRefreshTokenInvalid
+			}
+			return err
+		}
+
+		now := time.Now()
+		if !session.IsActive(now) {
+			return ErrRefreshTokenInvalid
+		}
+		if token.UsedAt != nil {
+			// Pencabutan disimpan, sehingga transaksi tetap di-commit
+			reused = true
+			return revokeSessions(tx.Where("id = ?", session.ID), SessionRevokedReuse, now).Error
+		}
+		if !now.Before(token.ExpiresAt) {
+			return ErrRefreshTokenInvalid
+		}
+
+		if err := tx.Model(&model.RefreshToken{}).Where("id = ?", token.ID).Update("used_at", now).Error; err != nil {
+			return err
+		}
+		session.LastUsedAt = now
+		session.ExpiresAt = now.Add(ttl)
+		if err := tx.Model(&model.Session{}).Where("id = ?", session.ID).Updates(map[string]interface{}{
+			"last_used_at": session.LastUsedAt,
+			"expires_at":   session.ExpiresAt,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&model.RefreshToken{
+			SessionID: session.ID,
+			TokenHash: utils.HashToken(newRefreshToken),
+			ExpiresAt: session.ExpiresAt,
+		}).Error
+	})
+	if err != nil {
+		return session, err
+	}
+	if reused {
+		return session, ErrRefreshTokenReused
+	}
+	return session, nil
+}
+
+// IsSessionActive mengembalikan true jika sesi milik userID belum dicabut dan belum kedaluwarsa
+func (sr sessionRepository) IsSessionActive(sessionID, userID uint) (bool, error) {
+	var count int64
+	err := sr.DB.Model(&model.Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL AND expires_at > ?", sessionID, userID, time.Now()).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// GetActiveSessions mengembalikan sesi aktif milik user, yang terakhir dipakai lebih dulu
+func (sr sessionRepository) GetActiveSessions(userID uint) (sessions []model.Session, err error) {
+	return sessions, sr.DB.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_used_at DESC").Find(&sessions).Error
+}
+
+// RevokeSession mencabut satu sesi aktif milik user
+func (sr sessionRepository) RevokeSession(userID, sessionID uint, reason string) error {
+	result := revokeSessions(sr.DB.Where("id = ? AND user_id = ?", sessionID, userID), reason, time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// RevokeSessionByRefreshToken mencabut sesi pemilik refresh token yang belum
+// dipakai, sehingga client dapat logout walaupun access token-nya sudah kedaluwarsa
+func (sr sessionRepository) RevokeSessionByRefreshToken(refreshToken, reason string) error {
+	var token model.RefreshToken
+	if err := sr.DB.Where("token_hash = ? AND used_at IS NULL", utils.HashToken(refreshToken)).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrRefreshTokenInvalid
+		}
+		return err
+	}
+	return revokeSessions(sr.DB.Where("id = ?", token.SessionID), reason, time.Now()).Error
+}
+
+// RevokeAllSessions mencabut semua sesi aktif milik user
+func (sr sessionRepository) RevokeAllSessions(userID uint, reason string) (int64, error) {
+	result := revokeSessions(sr.DB.Where("user_id = ?", userID), reason, time.Now())
+	return result.RowsAffected, result.Error
+}
+
+// RevokeOtherSessions mencabut semua sesi aktif milik user kecuali
+// keepSessionID; keepSessionID 0 berarti semua sesi dicabut
+func (sr sessionRepository) RevokeOtherSessions(userID, keepSessionID uint, reason string) (int64, error) {
+	result := revokeSessions(sr.DB.Where("user_id = ? AND id <> ?", userID, keepSessionID), reason, time.Now())
+	return result.RowsAffected, result.Error
+}
+
+// revokeSessions mencabut sesi yang belum dicabut dari query
+func revokeSessions(query *gorm.DB, reason string, now time.Time) *gorm.DB {
+	return query.Model(&model.Session{}).Where("revoked_at IS NULL").Updates(map[string]interface{}{
+		"revoked_at":     now,
+		"revoked_reason": reason,
+	})
+}
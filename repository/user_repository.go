@@ -1,12 +1,20 @@
 package repository
 
 import (
+	"errors"
 	"log"
+	"time"
 
 	"github.com/gamaput/go-redeem/model"
+	"github.com/gamaput/go-redeem/utils"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrUserTokenInvalid dikembalikan jika token reset password atau verifikasi
+// email tidak dikenal, sudah dipakai, atau kedaluwarsa
+var ErrUserTokenInvalid = errors.New("invalid or expired token")
+
 type userRepository struct {
 	DB *gorm.DB
 }
@@ -19,6 +27,14 @@ type UserRepository interface {
 	GetAllUser() ([]model.User, error)
 	UpdateUser(model.User) (model.User, error)
 	DeleteUser(model.User) (model.User, error)
+	CreateUserToken(userID uint, purpose, token string, ttl time.Duration) error
+	ResetPassword(token, hashedPassword string) (model.User, error)
+	VerifyEmail(token string) (model.User, error)
+	ChangeRole(userID uint, role string, grantedBy uint) (model.User, error)
+	GetRoleGrants(userID uint) ([]model.RoleGrant, error)
+	GetUnconfirmedRoles(defaultRole string) ([]model.User, error)
+	CountUsersWithRole(role string) (int64, error)
+	BootstrapRole(userID uint, role string) (model.User, error)
 	Migrate() error
 }
 
@@ -50,11 +66,41 @@ func (u userRepository) AddUser(user model.User) (model.User, error) {
 	return user, u.DB.Create(&user).Error
 }
 
-func (u userRepository) UpdateUser(user model.User) (model.User, error) {
-	if err := u.DB.Model(&model.User{}).Where("id = ?", user.ID).Updates(&user).Error; err != nil {
-		return user, err
-	}
-	return user, nil
+// UpdateUser mengubah nama, email, dan password user yang diisi. Jika email
+// berubah, status verifikasi dikosongkan dan token verifikasi yang dikirim ke
+// email lama tidak berlaku lagi.
+func (u userRepository) UpdateUser(user model.User) (updated model.User, err error) {
+	err = u.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&updated, user.ID).Error; err != nil {
+			return err
+		}
+
+		// Map dipakai agar email_verified_at dapat diisi NULL
+		changes := map[string]interface{}{}
+		if user.Name != "" {
+			changes["name"] = user.Name
+		}
+		if user.Password != "" {
+			changes["password"] = user.Password
+		}
+		if user.Email != "" && user.Email != updated.Email {
+			changes["email"] = user.Email
+			changes["email_verified_at"] = nil
+			if err := tx.Model(&model.UserToken{}).
+				Where("user_id = ? AND purpose = ? AND used_at IS NULL", user.ID, model.UserTokenEmailVerification).
+				Update("used_at", time.Now()).Error; err != nil {
+				return err
+			}
+		}
+		if len(changes) == 0 {
+			return nil
+		}
+		if err := tx.Model(&model.User{}).Where("id = ?", user.ID).Updates(changes).Error; err != nil {
+			return err
+		}
+		return tx.First(&updated, user.ID).Error
+	})
+	return updated, err
 }
 
 func (u userRepository) DeleteUser(user model.User) (model.User, error) {
@@ -63,3 +109,153 @@ func (u userRepository) DeleteUser(user model.User) (model.User, error) {
 	}
 	return user, u.DB.Delete(&user).Error
 }
+
+// ChangeRole mengganti role user dan mencatat admin yang memberikannya.
+// Role harus sudah divalidasi terhadap Casbin oleh pemanggil.
+func (u userRepository) ChangeRole(userID uint, role string, grantedBy uint) (model.User, error) {
+	return u.grantRole(userID, model.RoleGrant{Role: role, GrantedBy: grantedBy})
+}
+
+// BootstrapRole memberikan role kepada admin pertama dan mencatatnya dengan
+// alasan RoleGrantReasonBootstrap
+func (u userRepository) BootstrapRole(userID uint, role string) (model.User, error) {
+	return u.grantRole(userID, model.RoleGrant{Role: role, Reason: model.RoleGrantReasonBootstrap})
+}
+
+// grantRole mengganti role user dan mencatat grant di riwayat role dalam satu transaksi
+func (u userRepository) grantRole(userID uint, grant model.RoleGrant) (user model.User, err error) {
+	err = u.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+			return err
+		}
+		grant.UserID = user.ID
+		grant.PreviousRole = user.Role
+		if err := tx.Create(&grant).Error; err != nil {
+			return err
+		}
+		user.Role = grant.Role
+		return tx.Model(&model.User{}).Where("id = ?", user.ID).Update("role", grant.Role).Error
+	})
+	user.Password = ""
+	return user, err
+}
+
+// GetRoleGrants mengembalikan riwayat perubahan role user, terbaru lebih dulu
+func (u userRepository) GetRoleGrants(userID uint) (grants []model.RoleGrant, err error) {
+	return grants, u.DB.Where("user_id = ?", userID).Order("id DESC").Find(&grants).Error
+}
+
+// GetUnconfirmedRoles mengembalikan user yang role-nya bukan defaultRole dan
+// tidak pernah diberikan lewat ChangeRole atau BootstrapRole: role yang hanya
+// tercatat oleh migrasi (mungkin dipilih sendiri lewat /api/register versi
+// lama) atau diubah langsung di database. Role user tidak diubah.
+func (u userRepository) GetUnconfirmedRoles(defaultRole string) (users []model.User, err error) {
+	err = u.DB.Where("role <> ?", defaultRole).
+		Where("NOT EXISTS (SELECT 1 FROM role_grants WHERE role_grants.user_id = users.id AND role_grants.role = users.role AND role_grants.reason <> ? AND role_grants.deleted_at IS NULL)", model.RoleGrantReasonMigration).
+		Order("id").Find(&users).Error
+	for i := range users {
+		users[i].Password = ""
+	}
+	return users, err
+}
+
+// CountUsersWithRole menghitung user yang memiliki role
+func (u userRepository) CountUsersWithRole(role string) (count int64, err error) {
+	return count, u.DB.Model(&model.User{}).Where("role = ?", role).Count(&count).Error
+}
+
+// CreateUserToken menyimpan hash token baru untuk user. Token lama dengan
+// kegunaan yang sama yang belum dipakai tidak berlaku lagi, sehingga hanya
+// tautan terakhir yang bisa dipakai.
+func (u userRepository) CreateUserToken(userID uint, purpose, token string, ttl time.Duration) error {
+	return u.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Model(&model.UserToken{}).
+			Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+			Update("used_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Create(&model.UserToken{
+			UserID:    userID,
+			Purpose:   purpose,
+			TokenHash: utils.HashToken(token),
+			ExpiresAt: now.Add(ttl),
+		}).Error
+	})
+}
+
+// ResetPassword mengganti password user pemilik token reset password,
+// membuka lockout sign in akunnya, dan mencabut semua sesinya. Token hanya
+// bisa dipakai sekali.
+func (u userRepository) ResetPassword(token, hashedPassword string) (user model.User, err error) {
+	err = u.DB.Transaction(func(tx *gorm.DB) error {
+		userToken, err := consumeUserToken(tx, model.UserTokenPasswordReset, token)
+		if err != nil {
+			return err
+		}
+		if err := tx.First(&user, userToken.UserID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrUserTokenInvalid
+			}
+			return err
+		}
+		now := time.Now()
+		updates := map[string]interface{}{"password": hashedPassword}
+		// Tautan reset dikirim ke email user, sehingga email tersebut terbukti miliknya
+		if user.EmailVerifiedAt == nil {
+			updates["email_verified_at"] = now
+			user.EmailVerifiedAt = &now
+		}
+		if err := tx.Model(&model.User{}).Where("id = ?", user.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+		if err := unlockAccountThrottles(tx, user.ID, "password reset"); err != nil {
+			return err
+		}
+		return revokeSessions(tx.Where("user_id = ?", user.ID), SessionRevokedReset, now).Error
+	})
+	user.Password = ""
+	return user, err
+}
+
+// VerifyEmail menandai email user pemilik token verifikasi sebagai terverifikasi
+func (u userRepository) VerifyEmail(token string) (user model.User, err error) {
+	err = u.DB.Transaction(func(tx *gorm.DB) error {
+		userToken, err := consumeUserToken(tx, model.UserTokenEmailVerification, token)
+		if err != nil {
+			return err
+		}
+		if err := tx.First(&user, userToken.UserID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrUserTokenInvalid
+			}
+			return err
+		}
+		if user.EmailVerifiedAt != nil {
+			return nil
+		}
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+		return tx.Model(&model.User{}).Where("id = ?", user.ID).Update("email_verified_at", now).Error
+	})
+	user.Password = ""
+	return user, err
+}
+
+// consumeUserToken mengunci token dengan kegunaan purpose, memastikan belum
+// dipakai dan belum kedaluwarsa, lalu menandainya sudah dipakai
+func consumeUserToken(tx *gorm.DB, purpose, token string) (userToken model.UserToken, err error) {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("token_hash = ? AND purpose = ?", utils.HashToken(token), purpose).
+		First(&userToken).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return userToken, ErrUserTokenInvalid
+		}
+		return userToken, err
+	}
+	now := time.Now()
+	if userToken.UsedAt != nil || !now.Before(userToken.ExpiresAt) {
+		return userToken, ErrUserTokenInvalid
+	}
+	return userToken, tx.Model(&model.UserToken{}).Where("id = ?", userToken.ID).Update("used_at", now).Error
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gamaput/go-redeem/model"
+)
+
+func TestUpdateUserEmailChangeClearsVerification(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewUserRepository(db)
+
+	verifiedAt := time.Now()
+	user, err := repo.AddUser(model.User{Name: "Budi", Email: "budi@example.com", Role: "user", EmailVerifiedAt: &verifiedAt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.CreateUserToken(user.ID, model.UserTokenEmailVerification, "old-address-token", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	// Mengubah nama saja tidak mengubah status verifikasi
+	updated, err := repo.UpdateUser(model.User{Model: user.Model, Name: "Budi Santoso", Email: user.Email})
+	if err != nil {
+		t.Fatalf("UpdateUser(name) error = %v", err)
+	}
+	if updated.Name != "Budi Santoso" || updated.EmailVerifiedAt == nil {
+		t.Fatalf("UpdateUser(name) = %+v, want new name and email still verified", updated)
+	}
+
+	updated, err = repo.UpdateUser(model.User{Model: user.Model, Email: "budi.new@example.com"})
+	if err != nil {
+		t.Fatalf("UpdateUser(email) error = %v", err)
+	}
+	if updated.Email != "budi.new@example.com" || updated.Name != "Budi Santoso" {
+		t.Errorf("UpdateUser(email) = %+v, want new email and unchanged name", updated)
+	}
+	if updated.EmailVerifiedAt != nil {
+		t.Errorf("email_verified_at = %v after email change, want NULL", updated.EmailVerifiedAt)
+	}
+
+	// Token verifikasi yang dikirim ke email lama tidak boleh memverifikasi email baru
+	if _, err := repo.VerifyEmail("old-address-token"); !errors.Is(err, ErrUserTokenInvalid) {
+		t.Errorf("VerifyEmail(old token) error = %v, want %v", err, ErrUserTokenInvalid)
+	}
+}
+
+func TestGetUnconfirmedRoles(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewUserRepository(db)
+
+	add := func(email, role string) model.User {
+		user, err := repo.AddUser(model.User{Name: email, Email: email, Role: role})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return user
+	}
+	admin := add("admin@example.com", "user")
+	granted := add("granted@example.com", "user")
+	if _, err := repo.ChangeRole(granted.ID, "admin", admin.ID); err != nil {
+		t.Fatal(err)
+	}
+	bootstrapped := add("bootstrap@example.com", "user")
+	if _, err := repo.BootstrapRole(bootstrapped.ID, "admin"); err != nil {
+		t.Fatal(err)
+	}
+	// Role yang sudah ada sebelum tabel role_grants dibuat
+	migrated := add("migrated@example.com", "admin")
+	if err := db.Create(&model.RoleGrant{UserID: migrated.ID, Role: "admin", Reason: model.RoleGrantReasonMigration}).Error; err != nil {
+		t.Fatal(err)
+	}
+	// Role yang diubah langsung di database
+	edited := add("edited@example.com", "admin")
+
+	users, err := repo.GetUnconfirmedRoles("user")
+	if err != nil {
+		t.Fatalf("GetUnconfirmedRoles() error = %v", err)
+	}
+	got := map[uint]bool{}
+	for _, user := range users {
+		got[user.ID] = true
+		if user.Password != "" {
+			t.Errorf("user %d password is not cleared", user.ID)
+		}
+	}
+	if len(got) != 2 || !got[migrated.ID] || !got[edited.ID] {
+		t.Errorf("GetUnconfirmedRoles() = %+v, want users %d and %d", users, migrated.ID, edited.ID)
+	}
+
+	// Audit tidak mengubah role siapa pun
+	for _, want := range []struct {
+		id   uint
+		role string
+	}{{granted.ID, "admin"}, {bootstrapped.ID, "admin"}, {migrated.ID, "admin"}, {edited.ID, "admin"}, {admin.ID, "user"}} {
+		user, err := repo.GetUser(int(want.id))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if user.Role != want.role {
+			t.Errorf("user %d role = %q, want %q", want.id, user.Role, want.role)
+		}
+	}
+
+	if count, err := repo.CountUsersWithRole("admin"); err != nil || count != 4 {
+		t.Errorf("CountUsersWithRole(admin) = %d, %v, want 4", count, err)
+	}
+	var grant model.RoleGrant
+	if err := db.Where("user_id = ?", bootstrapped.ID).First(&grant).Error; err != nil {
+		t.Fatal(err)
+	}
+	if grant.Reason != model.RoleGrantReasonBootstrap || grant.GrantedBy != 0 || grant.PreviousRole != "user" {
+		t.Errorf("bootstrap grant = %+v, want reason %q from role user", grant, model.RoleGrantReasonBootstrap)
+	}
+}
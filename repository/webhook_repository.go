@@ -0,0 +1,254 @@
+package repository
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gamaput/go-redeem/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// skipLocked mengunci baris yang dipilih dan melewati baris yang sedang
+// dikunci worker lain, sehingga beberapa instance dapat berjalan bersamaan
+var skipLocked = clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}
+
+// DeliveryFilter membatasi pengiriman webhook yang dikembalikan; field bernilai kosong diabaikan
+type DeliveryFilter struct {
+	SubscriptionID uint
+	EventID        uint
+	Status         string
+}
+
+// DueDelivery adalah pengiriman webhook yang siap dicoba beserta langganan dan event-nya.
+// Subscription bernilai kosong jika langganan sudah dihapus.
+type DueDelivery struct {
+	Delivery     model.WebhookDelivery
+	Subscription model.WebhookSubscription
+	Event        model.OutboxEvent
+}
+
+type webhookRepository struct {
+	DB *gorm.DB
+}
+
+// WebhookRepository : represent the webhook's repository contract
+type WebhookRepository interface {
+	CreateSubscription(*model.WebhookSubscription) error
+	GetAllSubscriptions() ([]model.WebhookSubscription, error)
+	GetSubscriptionByID(uint) (model.WebhookSubscription, error)
+	UpdateSubscription(model.WebhookSubscription) error
+	DeleteSubscription(uint) error
+
+	PublishOutboxEvents(limit int) (int, error)
+	ClaimDueDeliveries(now time.Time, lease time.Duration, limit int) ([]DueDelivery, error)
+	RecordDeliveryAttempt(delivery model.WebhookDelivery, attempt model.WebhookDeliveryAttempt) error
+	GetDeliveries(DeliveryFilter) ([]model.WebhookDelivery, error)
+	GetDeliveryByID(uint) (model.WebhookDelivery, error)
+	RetryDelivery(uint) error
+}
+
+// NewWebhookRepository -> returns new webhook repository
+func NewWebhookRepository(db *gorm.DB) WebhookRepository {
+	return webhookRepository{
+		DB: db,
+	}
+}
+
+func (wr webhookRepository) CreateSubscription(subscription *model.WebhookSubscription) error {
+	return wr.DB.Create(subscription).Error
+}
+
+func (wr webhookRepository) GetAllSubscriptions() (subscriptions []model.WebhookSubscription, err error) {
+	return subscriptions, wr.DB.Order("id").Find(&subscriptions).Error
+}
+
+func (wr webhookRepository) GetSubscriptionByID(id uint) (subscription model.WebhookSubscription, err error) {
+	return subscription, wr.DB.First(&subscription, id).Error
+}
+
+func (wr webhookRepository) UpdateSubscription(subscription model.WebhookSubscription) error {
+	return wr.DB.Model(&model.WebhookSubscription{}).Where("id = ?", subscription.ID).Updates(map[string]interface{}{
+		"url":         subscription.URL,
+		"secret":      subscription.Secret,
+		"event_types": subscription.EventTypes,
+		"description": subscription.Description,
+		"active":      subscription.Active,
+	}).Error
+}
+
+func (wr webhookRepository) DeleteSubscription(id uint) error {
+	return wr.DB.Delete(&model.WebhookSubscription{}, id).Error
+}
+
+// PublishOutboxEvents membuat pengiriman untuk setiap langganan aktif dari
+// paling banyak limit event outbox yang belum dipublikasikan, lalu menandai
+// event tersebut sebagai sudah dipublikasikan dalam satu transaksi
+func (wr webhookRepository) PublishOutboxEvents(limit int) (published int, err error) {
+	err = wr.DB.Transaction(func(tx *gorm.DB) error {
+		var events []model.OutboxEvent
+		if err := tx.Clauses(skipLocked).Where("published_at IS NULL").Order("id").Limit(limit).Find(&events).Error; err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
+		}
+
+		var subscriptions []model.WebhookSubscription
+		if err := tx.Where("active = ?", true).Find(&subscriptions).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		ids := make([]uint, 0, len(events))
+		var deliveries []model.WebhookDelivery
+		for _, event := range events {
+			ids = append(ids, event.ID)
+			for _, subscription := range subscriptions {
+				if !subscription.Subscribes(event.Type) {
+					continue
+				}
+				deliveries = append(deliveries, model.WebhookDelivery{
+					SubscriptionID: subscription.ID,
+					EventID:        event.ID,
+					EventType:      event.Type,
+					Status:         model.DeliveryStatusPending,
+					NextAttemptAt:  now,
+				})
+			}
+		}
+		if len(deliveries) > 0 {
+			if err := tx.CreateInBatches(deliveries, 500).Error; err != nil {
+				return err
+			}
+		}
+		published = len(events)
+		return tx.Model(&model.OutboxEvent{}).Where("id IN ?", ids).Update("published_at", now).Error
+	})
+	return published, err
+}
+
+// ClaimDueDeliveries mengambil paling banyak limit pengiriman yang sudah
+// waktunya dicoba dan menunda percobaan berikutnya selama lease, sehingga
+// pengiriman yang terputus karena proses berhenti akan dicoba lagi setelah lease berakhir
+func (wr webhookRepository) ClaimDueDeliveries(now time.Time, lease time.Duration, limit int) (due []DueDelivery, err error) {
+	err = wr.DB.Transaction(func(tx *gorm.DB) error {
+		var deliveries []model.WebhookDelivery
+		if err := tx.Clauses(skipLocked).
+			Where("status = ? AND next_attempt_at <= ?", model.DeliveryStatusPending, now).
+			Order("next_attempt_at").Limit(limit).Find(&deliveries).Error; err != nil {
+			return err
+		}
+		if len(deliveries) == 0 {
+			return nil
+		}
+
+		deliveryIDs := make([]uint, 0, len(deliveries))
+		subscriptionIDs := make([]uint, 0, len(deliveries))
+		eventIDs := make([]uint, 0, len(deliveries))
+		for _, delivery := range deliveries {
+			deliveryIDs = append(deliveryIDs, delivery.ID)
+			subscriptionIDs = append(subscriptionIDs, delivery.SubscriptionID)
+			eventIDs = append(eventIDs, delivery.EventID)
+		}
+		if err := tx.Model(&model.WebhookDelivery{}).Where("id IN ?", deliveryIDs).
+			Update("next_attempt_at", now.Add(lease)).Error; err != nil {
+			return err
+		}
+
+		var subscriptions []model.WebhookSubscription
+		if err := tx.Where("id IN ?", subscriptionIDs).Find(&subscriptions).Error; err != nil {
+			return err
+		}
+		var events []model.OutboxEvent
+		if err := tx.Where("id IN ?", eventIDs).Find(&events).Error; err != nil {
+			return err
+		}
+		subscriptionsByID := make(map[uint]model.WebhookSubscription, len(subscriptions))
+		for _, subscription := range subscriptions {
+			subscriptionsByID[subscription.ID] = subscription
+		}
+		eventsByID := make(map[uint]model.OutboxEvent, len(events))
+		for _, event := range events {
+			eventsByID[event.ID] = event
+		}
+
+		for _, delivery := range deliveries {
+			due = append(due, DueDelivery{
+				Delivery:     delivery,
+				Subscription: subscriptionsByID[delivery.SubscriptionID],
+				Event:        eventsByID[delivery.EventID],
+			})
+		}
+		return nil
+	})
+	return due, err
+}
+
+// RecordDeliveryAttempt menyimpan hasil satu percobaan pengiriman. delivery
+// berisi status, jumlah percobaan dan jadwal percobaan berikutnya yang baru.
+func (wr webhookRepository) RecordDeliveryAttempt(delivery model.WebhookDelivery, attempt model.WebhookDeliveryAttempt) error {
+	return wr.DB.Transaction(func(tx *gorm.DB) error {
+		attempt.DeliveryID = delivery.ID
+		if err := tx.Create(&attempt).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.WebhookDelivery{}).Where("id = ?", delivery.ID).Updates(map[string]interface{}{
+			"status":           delivery.Status,
+			"attempts":         delivery.Attempts,
+			"next_attempt_at":  delivery.NextAttemptAt,
+			"last_status_code": delivery.LastStatusCode,
+			"last_error":       delivery.LastError,
+			"delivered_at":     delivery.DeliveredAt,
+		}).Error
+	})
+}
+
+// GetDeliveries mengembalikan pengiriman webhook terbaru lebih dulu
+func (wr webhookRepository) GetDeliveries(filter DeliveryFilter) (deliveries []model.WebhookDelivery, err error) {
+	query := wr.DB.Order("id DESC").Limit(1000)
+	if filter.SubscriptionID != 0 {
+		query = query.Where("subscription_id = ?", filter.SubscriptionID)
+	}
+	if filter.EventID != 0 {
+		query = query.Where("event_id = ?", filter.EventID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	return deliveries, query.Find(&deliveries).Error
+}
+
+// GetDeliveryByID mengembalikan pengiriman webhook beserta log percobaannya
+func (wr webhookRepository) GetDeliveryByID(id uint) (delivery model.WebhookDelivery, err error) {
+	return delivery, wr.DB.Preload("Log", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).First(&delivery, id).Error
+}
+
+// RetryDelivery menjadwalkan ulang pengiriman yang sudah dead untuk segera dicoba lagi
+func (wr webhookRepository) RetryDelivery(id uint) error {
+	result := wr.DB.Model(&model.WebhookDelivery{}).Where("id = ? AND status = ?", id, model.DeliveryStatusDead).
+		Updates(map[string]interface{}{
+			"status":          model.DeliveryStatusPending,
+			"attempts":        0,
+			"next_attempt_at": time.Now(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// enqueueOutboxEvent mencatat event di outbox di dalam transaksi tx, sehingga
+// event hanya dikirim jika perubahan datanya ikut di-commit
+func enqueueOutboxEvent(tx *gorm.DB, eventType string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return tx.Create(&model.OutboxEvent{Type: eventType, Payload: string(payload)}).Error
+}
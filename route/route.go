@@ -3,11 +3,20 @@ package route
 import (
 	"fmt"
 	"log"
+	"net/http"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/gamaput/go-redeem/controller"
+	"github.com/gamaput/go-redeem/jwtkeys"
+	"github.com/gamaput/go-redeem/mailer"
 	"github.com/gamaput/go-redeem/middleware"
+	"github.com/gamaput/go-redeem/notification"
+	"github.com/gamaput/go-redeem/phone"
 	"github.com/gamaput/go-redeem/repository"
+	"github.com/gamaput/go-redeem/utils"
+	"github.com/gamaput/go-redeem/webhook"
 
 	"github.com/casbin/casbin/v2"
 	gormadapter "github.com/casbin/gorm-adapter/v3"
@@ -18,13 +27,20 @@ import (
 
 // SetupRoutes : all the routes are defined here
 func SetupRoutes(db *gorm.DB) {
+	// Access token ditandatangani dengan key ring; server tidak dijalankan tanpa key
+	keyRing, err := jwtkeys.LoadFromEnv()
+	if err != nil {
+		log.Fatal("failed to load JWT signing keys: ", err)
+	}
+	utils.SetKeyRing(keyRing)
+
 	httpRouter := gin.Default()
 
 	httpRouter.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:3000"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
-		AllowHeaders:     []string{"Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Content-Type", "Authorization", "Idempotency-Key"},
+		ExposeHeaders:    []string{"Content-Length", "Retry-After", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Idempotent-Replayed"},
 		AllowCredentials: true,
 		AllowOriginFunc: func(origin string) bool {
 			return origin == "http://localhost:3000"
@@ -55,34 +71,216 @@ func SetupRoutes(db *gorm.DB) {
 		enforcer.AddPolicy("user", "report", "read")
 	}
 
+	// User baru selalu mendapat DEFAULT_USER_ROLE; role lain hanya bisa diberikan admin
+	defaultRole := os.Getenv("DEFAULT_USER_ROLE")
+	if defaultRole == "" {
+		defaultRole = "user"
+	}
+	if exists, err := controller.RoleExists(enforcer, defaultRole); err != nil {
+		log.Fatal("failed to load casbin policy: ", err)
+	} else if !exists {
+		log.Fatalf("DEFAULT_USER_ROLE: role %q does not exist in casbin policy", defaultRole)
+	}
+
+	// REQUIRE_ADMIN_MFA=true mewajibkan 2FA untuk role admin; policy juga bisa ditambahkan langsung ke Casbin
+	if os.Getenv("REQUIRE_ADMIN_MFA") == "true" && !enforcer.HasPolicy("admin", "mfa", "required") {
+		enforcer.AddPolicy("admin", "mfa", "required")
+	}
+
+	notifier := newNotifier()
+
 	userRepository := repository.NewUserRepository(db)
+	sessionRepository := repository.NewSessionRepository(db)
+	mfaRepository := repository.NewMFARepository(db)
+	// Sign in dikunci bertahap setelah kegagalan berulang per akun dan per IP
+	loginThrottleRepository := repository.NewLoginThrottleRepository(db,
+		repository.LoginLockoutPolicy{MaxFailures: 5, Window: 15 * time.Minute, BaseDuration: time.Minute, MaxDuration: time.Hour},
+		repository.LoginLockoutPolicy{MaxFailures: 20, Window: 15 * time.Minute, BaseDuration: time.Minute, MaxDuration: time.Hour},
+	)
 	productRepository := repository.NewProductRepository(db)
-	redeemCodeRepository := repository.NewRedeemCodeRepository(db)
-	prizeCodeRepository := repository.NewPrizeRepository(db)
+	redeemCodeRepository := repository.NewRedeemCodeRepository(db, notifier)
+	prizeCodeRepository := repository.NewPrizeRepository(db, notifier)
+	campaignRepository := repository.NewCampaignRepository(db)
+	codeBatchRepository := repository.NewCodeBatchRepository(db)
+	redemptionRepository := repository.NewRedemptionRepository(db)
+	participantRepository := repository.NewParticipantRepository(db)
+	idempotencyRepository := repository.NewIdempotencyRepository(db)
+	webhookRepository := repository.NewWebhookRepository(db)
+	partnerRepository := repository.NewPartnerRepository(db)
 
 	if err := userRepository.Migrate(); err != nil {
 		log.Fatal("User migrate err", err)
 	}
+	// BOOTSTRAP_ADMIN_EMAIL memberi role admin kepada user terdaftar dengan
+	// email terverifikasi tersebut selama belum ada admin, lihat README
+	if email := strings.TrimSpace(os.Getenv("BOOTSTRAP_ADMIN_EMAIL")); email != "" {
+		if err := controller.BootstrapAdmin(enforcer, userRepository, email, "admin"); err != nil {
+			log.Println("BOOTSTRAP_ADMIN_EMAIL:", err)
+		}
+	}
+	// ROLE_AUDIT=true mencatat user yang role-nya perlu ditinjau, hasil yang
+	// sama dengan GET /api/users/role-audit
+	if os.Getenv("ROLE_AUDIT") == "true" {
+		findings, err := controller.AuditRoles(enforcer, userRepository, defaultRole)
+		if err != nil {
+			log.Fatal("role audit err: ", err)
+		}
+		for _, finding := range findings {
+			log.Printf("[RoleAudit] user %d (%s): role %q, casbin roles %v: %s", finding.UserID, finding.Email, finding.Role, finding.CasbinRoles, finding.Problem)
+		}
+	}
 
-	userController := controller.NewUserController(userRepository)
+	userController := controller.NewUserController(userRepository, sessionRepository, mfaRepository, loginThrottleRepository, mailer.FromEnv(), appURL())
 	productController := controller.NewProductController(productRepository)
-	redeemController := controller.NewRedeemCodeController(redeemCodeRepository, prizeCodeRepository)
-	prizeController := controller.NewPrizeController(prizeCodeRepository)
+	redeemController := controller.NewRedeemCodeController(redeemCodeRepository, prizeCodeRepository, campaignRepository, codeBatchRepository)
+	prizeController := controller.NewPrizeController(prizeCodeRepository, campaignRepository)
+	campaignController := controller.NewCampaignController(campaignRepository)
+	webhookController := controller.NewWebhookController(webhookRepository)
+	partnerController := controller.NewPartnerController(partnerRepository, campaignRepository)
+	redemptionController := controller.NewRedemptionController(redemptionRepository, participantRepository, campaignRepository, prizeCodeRepository)
+
+	// Worker mengirim event dari outbox ke pelanggan webhook
+	go webhook.NewWorker(webhookRepository).Run(nil)
+
+	// Rate limit endpoint publik; batas dapat diubah lewat env dengan format "<jumlah>/<durasi>"
+	rateLimitStore := middleware.NewMemoryRateLimitStore()
+	publicLimit := middleware.RateLimiter(middleware.RateLimitConfig{
+		Name:  "public",
+		Store: rateLimitStore,
+		Limit: rateLimitFromEnv("PUBLIC_RATE_LIMIT", "60/1m"),
+	})
+	redeemLimit := middleware.RateLimiter(middleware.RateLimitConfig{
+		Name:          "redeem",
+		Store:         rateLimitStore,
+		Limit:         rateLimitFromEnv("REDEEM_RATE_LIMIT", "10/1m"),
+		IdentityLimit: rateLimitFromEnv("REDEEM_IDENTITY_RATE_LIMIT", "5/1m"),
+		IdentityFields: []middleware.IdentityField{
+			{Name: "no_ktp"},
+			{Name: "phone_no", Normalize: normalizePhoneKey},
+		},
+		Lockout: middleware.LockoutPolicy{
+			MaxFailures:  5,
+			Window:       15 * time.Minute,
+			BaseDuration: time.Minute,
+			MaxDuration:  time.Hour,
+		},
+	})
+
+	// Respons request dengan Idempotency-Key disimpan selama IDEMPOTENCY_TTL
+	idempotencyTTL := durationFromEnv("IDEMPOTENCY_TTL", 24*time.Hour)
+	idempotent := middleware.Idempotency(idempotencyRepository, idempotencyTTL)
+	go func() {
+		for now := range time.Tick(time.Hour) {
+			if _, err := idempotencyRepository.DeleteExpiredIdempotencyKeys(now); err != nil {
+				log.Println("failed to delete expired idempotency keys:", err)
+			}
+		}
+	}()
+	// Catatan challenge token 2FA yang sudah ditukar hanya perlu disimpan sampai token-nya kedaluwarsa
+	go func() {
+		for now := range time.Tick(time.Hour) {
+			if _, err := mfaRepository.DeleteExpiredMFAChallenges(now); err != nil {
+				log.Println("failed to delete expired mfa challenges:", err)
+			}
+		}
+	}()
+
+	statusLimit := middleware.RateLimiter(middleware.RateLimitConfig{
+		Name:          "redemption-status",
+		Store:         rateLimitStore,
+		Limit:         rateLimitFromEnv("STATUS_RATE_LIMIT", "20/1m"),
+		IdentityLimit: rateLimitFromEnv("STATUS_CODE_RATE_LIMIT", "5/1m"),
+		IdentityFields: []middleware.IdentityField{
+			{Name: "code", Normalize: normalizeCodeKey},
+		},
+		Lockout: middleware.LockoutPolicy{
+			MaxFailures:  5,
+			Window:       15 * time.Minute,
+			BaseDuration: time.Minute,
+			MaxDuration:  time.Hour,
+		},
+	})
+
+	// Public key untuk memverifikasi access token di layanan lain
+	httpRouter.GET("/.well-known/jwks.json", publicLimit, func(ctx *gin.Context) {
+		ctx.Header("Cache-Control", "public, max-age=300")
+		ctx.JSON(http.StatusOK, keyRing.JWKS())
+	})
+
+	// Permintaan reset password dibatasi per email agar tidak dipakai untuk membanjiri inbox user
+	passwordResetLimit := middleware.RateLimiter(middleware.RateLimitConfig{
+		Name:          "forgot-password",
+		Store:         rateLimitStore,
+		Limit:         rateLimitFromEnv("FORGOT_PASSWORD_RATE_LIMIT", "10/1h"),
+		IdentityLimit: rateLimitFromEnv("FORGOT_PASSWORD_EMAIL_RATE_LIMIT", "3/1h"),
+		IdentityFields: []middleware.IdentityField{
+			{Name: "email", Normalize: normalizeEmailKey},
+		},
+	})
+
+	// Kode 2FA hanya 6 digit, sehingga percobaan yang salah dikunci per user
+	mfaLimit := middleware.RateLimiter(middleware.RateLimitConfig{
+		Name:          "mfa",
+		Store:         rateLimitStore,
+		Limit:         rateLimitFromEnv("MFA_RATE_LIMIT", "10/1m"),
+		IdentityLimit: rateLimitFromEnv("MFA_USER_RATE_LIMIT", "5/1m"),
+		IdentityFields: []middleware.IdentityField{
+			{Name: "mfa_token", Normalize: normalizeMFATokenKey},
+		},
+		Lockout: middleware.LockoutPolicy{
+			MaxFailures:  5,
+			Window:       15 * time.Minute,
+			BaseDuration: time.Minute,
+			MaxDuration:  time.Hour,
+		},
+	})
 
 	apiRoutes := httpRouter.Group("/api")
 
 	{
-		apiRoutes.POST("/register", userController.AddUser(enforcer))
-		apiRoutes.POST("/signin", userController.SignInUser)
-		apiRoutes.GET("/logout", userController.Logout)
-		apiRoutes.POST("/redeem", redeemController.RedeemCode)
-		apiRoutes.GET("/rand-prize", prizeController.GetRandomPrize)
+		apiRoutes.POST("/register", publicLimit, userController.AddUser(enforcer, defaultRole))
+		apiRoutes.POST("/signin", publicLimit, userController.SignInUser(enforcer))
+		apiRoutes.POST("/signin/mfa", mfaLimit, userController.VerifyMFA)
+		apiRoutes.POST("/signin/mfa/enroll", publicLimit, userController.BeginMFAEnrollment)
+		apiRoutes.POST("/refresh", publicLimit, userController.RefreshToken)
+		apiRoutes.POST("/forgot-password", passwordResetLimit, userController.ForgotPassword)
+		apiRoutes.POST("/reset-password", publicLimit, userController.ResetPassword)
+		apiRoutes.POST("/verify-email", publicLimit, userController.VerifyEmail)
+		apiRoutes.POST("/verify-email/resend", publicLimit, middleware.AuthorizeJWT(sessionRepository), userController.ResendVerification)
+		apiRoutes.GET("/logout", middleware.AuthorizeJWT(sessionRepository), userController.Logout)
+		apiRoutes.POST("/logout", middleware.OptionalJWT(sessionRepository), userController.Logout)
+		apiRoutes.POST("/redeem", redeemLimit, idempotent, redeemController.RedeemCode)
+		apiRoutes.GET("/rand-prize", publicLimit, prizeController.GetRandomPrize)
+		apiRoutes.POST("/redemptions/status", statusLimit, redemptionController.LookupRedemptionStatus)
 	}
 
-	userProtectedRoutes := apiRoutes.Group("/users", middleware.AuthorizeJWT())
+	mfaRoutes := apiRoutes.Group("/mfa", middleware.AuthorizeJWT(sessionRepository))
 	{
+		mfaRoutes.GET("/", userController.GetMFAStatus)
+		mfaRoutes.POST("/totp", userController.EnrollTOTP)
+		mfaRoutes.POST("/totp/confirm", mfaLimit, userController.ConfirmTOTP)
+		mfaRoutes.DELETE("/totp", mfaLimit, userController.DisableTOTP(enforcer))
+		mfaRoutes.POST("/recovery-codes", mfaLimit, userController.RegenerateRecoveryCodes)
+	}
+
+	sessionRoutes := apiRoutes.Group("/sessions", middleware.AuthorizeJWT(sessionRepository))
+	{
+		sessionRoutes.GET("/", userController.GetMySessions)
+		sessionRoutes.DELETE("/:session", userController.RevokeMySession)
+	}
+
+	userProtectedRoutes := apiRoutes.Group("/users", middleware.AuthorizeJWT(sessionRepository))
+	{
+		userProtectedRoutes.GET("/lockouts", middleware.Authorize("report", "write", enforcer), userController.GetLoginLockouts)
+		userProtectedRoutes.GET("/role-audit", middleware.Authorize("report", "write", enforcer), userController.GetRoleAudit(enforcer, defaultRole))
+		userProtectedRoutes.DELETE("/lockouts/:lockout", middleware.Authorize("report", "write", enforcer), userController.UnlockLogin)
+		userProtectedRoutes.GET("/:user/lockouts", middleware.Authorize("report", "write", enforcer), userController.GetUserLockoutEvents)
+		userProtectedRoutes.PUT("/:user/role", middleware.Authorize("report", "write", enforcer), userController.ChangeUserRole(enforcer))
+		userProtectedRoutes.GET("/:user/role-grants", middleware.Authorize("report", "write", enforcer), userController.GetUserRoleGrants)
+		userProtectedRoutes.GET("/:user/sessions", middleware.Authorize("report", "write", enforcer), userController.GetUserSessions)
+		userProtectedRoutes.DELETE("/:user/sessions", middleware.Authorize("report", "write", enforcer), userController.RevokeUserSessions)
 		userProtectedRoutes.GET("/", middleware.Authorize("report", "read", enforcer), userController.GetAllUser)
-		userProtectedRoutes.POST("/add", middleware.Authorize("report", "write", enforcer), userController.AddUser(enforcer))
+		userProtectedRoutes.POST("/add", middleware.Authorize("report", "write", enforcer), userController.AddUser(enforcer, defaultRole))
 		userProtectedRoutes.GET("/:user", middleware.Authorize("report", "read", enforcer), userController.GetUser)
 
 		userProtectedRoutes.PATCH("/:user", middleware.Authorize("report", "write", enforcer), userController.UpdateUser)
@@ -91,7 +289,7 @@ func SetupRoutes(db *gorm.DB) {
 
 	}
 
-	productProductedRoutes := apiRoutes.Group("/products", middleware.AuthorizeJWT())
+	productProductedRoutes := apiRoutes.Group("/products", middleware.AuthorizeJWT(sessionRepository))
 	{
 		productProductedRoutes.GET("/", middleware.Authorize("report", "read", enforcer), productController.GetAllProducts)
 		productProductedRoutes.POST("/add", middleware.Authorize("report", "read", enforcer), productController.CreateProduct(enforcer))
@@ -100,20 +298,167 @@ func SetupRoutes(db *gorm.DB) {
 		productProductedRoutes.DELETE("/:product", middleware.Authorize("report", "write", enforcer), productController.DeleteProduct)
 
 	}
-	redeemCodeRoutes := apiRoutes.Group("/voucher", middleware.AuthorizeJWT())
+	redeemCodeRoutes := apiRoutes.Group("/voucher", middleware.AuthorizeJWT(sessionRepository))
 	{
 		redeemCodeRoutes.GET("/", middleware.Authorize("report", "read", enforcer), redeemController.GetAllRedeems)
+		redeemCodeRoutes.GET("/redemptions", middleware.Authorize("report", "read", enforcer), redemptionController.GetAllRedemptions)
+		redeemCodeRoutes.GET("/redemptions/:redemption", middleware.Authorize("report", "read", enforcer), redemptionController.GetRedemptionByID)
+		redeemCodeRoutes.GET("/redemptions/:redemption/fulfillment", middleware.Authorize("report", "read", enforcer), redemptionController.GetFulfillmentHistory)
+		redeemCodeRoutes.POST("/redemptions/:redemption/fulfillment", middleware.Authorize("report", "write", enforcer), redemptionController.TransitionFulfillment)
+		redeemCodeRoutes.GET("/participants", middleware.Authorize("report", "read", enforcer), redemptionController.GetAllParticipants)
+		redeemCodeRoutes.GET("/participants/:participant", middleware.Authorize("report", "read", enforcer), redemptionController.GetParticipantByID)
+		redeemCodeRoutes.POST("/participants/import", middleware.Authorize("report", "write", enforcer), redemptionController.ImportParticipants)
 		redeemCodeRoutes.GET("/generate-code", middleware.Authorize("report", "write", enforcer), redeemController.GenerateCode)
+		redeemCodeRoutes.POST("/generate-codes", middleware.Authorize("report", "write", enforcer), idempotent, redeemController.GenerateCodeBatch)
+		redeemCodeRoutes.PATCH("/batches/:batch/validity", middleware.Authorize("report", "write", enforcer), redeemController.UpdateBatchValidity)
+		redeemCodeRoutes.POST("/batches/:batch/revoke", middleware.Authorize("report", "write", enforcer), redeemController.RevokeBatch)
+		redeemCodeRoutes.POST("/batches/:batch/unrevoke", middleware.Authorize("report", "write", enforcer), redeemController.UnrevokeBatch)
+		redeemCodeRoutes.POST("/codes/:code/revoke", middleware.Authorize("report", "write", enforcer), redeemController.RevokeCode)
+		redeemCodeRoutes.POST("/codes/:code/unrevoke", middleware.Authorize("report", "write", enforcer), redeemController.UnrevokeCode)
 		// redeemCodeRoutes.POST("/redeem", middleware.Authorize("report", "read", enforcer), redeemController.RedeemCode)
 	}
-	prizeCodeRoutes := apiRoutes.Group("/prizes", middleware.AuthorizeJWT())
+	prizeCodeRoutes := apiRoutes.Group("/prizes", middleware.AuthorizeJWT(sessionRepository))
 	{
-		prizeCodeRoutes.POST("/add", middleware.Authorize("report", "write", enforcer), prizeController.CreatePrize)
+		prizeCodeRoutes.POST("/add", middleware.Authorize("report", "write", enforcer), idempotent, prizeController.CreatePrize)
 		prizeCodeRoutes.GET("/", middleware.Authorize("report", "write", enforcer), prizeController.GetAllPrizes)
 		prizeCodeRoutes.DELETE("/:prize", middleware.Authorize("report", "write", enforcer), prizeController.DeletePrize)
 		prizeCodeRoutes.PATCH("/:prize", middleware.Authorize("report", "write", enforcer), prizeController.UpdatePrize)
 		prizeCodeRoutes.GET("/:prize", middleware.Authorize("report", "write", enforcer), prizeController.GetPrizeByID)
+		prizeCodeRoutes.GET("/:prize/stock", middleware.Authorize("report", "read", enforcer), prizeController.GetStockMovements)
+		prizeCodeRoutes.POST("/:prize/stock", middleware.Authorize("report", "write", enforcer), idempotent, prizeController.RecordStockMovement)
+	}
+	campaignRoutes := apiRoutes.Group("/campaigns", middleware.AuthorizeJWT(sessionRepository))
+	{
+		campaignRoutes.GET("/", middleware.Authorize("report", "read", enforcer), campaignController.GetAllCampaigns)
+		campaignRoutes.POST("/add", middleware.Authorize("report", "write", enforcer), campaignController.CreateCampaign)
+		campaignRoutes.GET("/:campaign", middleware.Authorize("report", "read", enforcer), campaignController.GetCampaignByID)
+		campaignRoutes.PATCH("/:campaign", middleware.Authorize("report", "write", enforcer), campaignController.UpdateCampaign)
+		campaignRoutes.DELETE("/:campaign", middleware.Authorize("report", "write", enforcer), campaignController.DeleteCampaign)
+	}
+	webhookRoutes := apiRoutes.Group("/webhooks", middleware.AuthorizeJWT(sessionRepository))
+	{
+		webhookRoutes.GET("/", middleware.Authorize("report", "read", enforcer), webhookController.GetAllSubscriptions)
+		webhookRoutes.POST("/add", middleware.Authorize("report", "write", enforcer), webhookController.CreateSubscription)
+		webhookRoutes.GET("/deliveries", middleware.Authorize("report", "read", enforcer), webhookController.GetDeliveries)
+		webhookRoutes.GET("/deliveries/:delivery", middleware.Authorize("report", "read", enforcer), webhookController.GetDeliveryByID)
+		webhookRoutes.POST("/deliveries/:delivery/retry", middleware.Authorize("report", "write", enforcer), webhookController.RetryDelivery)
+		webhookRoutes.GET("/:subscription", middleware.Authorize("report", "read", enforcer), webhookController.GetSubscriptionByID)
+		webhookRoutes.PATCH("/:subscription", middleware.Authorize("report", "write", enforcer), webhookController.UpdateSubscription)
+		webhookRoutes.DELETE("/:subscription", middleware.Authorize("report", "write", enforcer), webhookController.DeleteSubscription)
+	}
+	partnerRoutes := apiRoutes.Group("/partners", middleware.AuthorizeJWT(sessionRepository))
+	{
+		partnerRoutes.GET("/", middleware.Authorize("report", "read", enforcer), partnerController.GetAllPartners)
+		partnerRoutes.POST("/add", middleware.Authorize("report", "write", enforcer), partnerController.CreatePartner)
+		partnerRoutes.GET("/:partner", middleware.Authorize("report", "read", enforcer), partnerController.GetPartnerByID)
+		partnerRoutes.PATCH("/:partner", middleware.Authorize("report", "write", enforcer), partnerController.UpdatePartner)
+		partnerRoutes.POST("/:partner/rotate-credentials", middleware.Authorize("report", "write", enforcer), partnerController.RotatePartnerCredentials)
+	}
+	// Endpoint untuk sistem POS partner, diautentikasi dengan API key dan tanda tangan HMAC
+	partnerReplayWindow := durationFromEnv("PARTNER_REPLAY_WINDOW", 5*time.Minute)
+	go func() {
+		// Nonce disimpan selama dua kali jendela replay, lihat UsePartnerNonce
+		for now := range time.Tick(partnerReplayWindow) {
+			if _, err := partnerRepository.DeleteExpiredPartnerNonces(now.Add(-2 * partnerReplayWindow)); err != nil {
+				log.Println("failed to delete expired partner nonces:", err)
+			}
+		}
+	}()
+	partnerAPIRoutes := apiRoutes.Group("/partner", middleware.AuthorizePartner(partnerRepository, partnerReplayWindow))
+	{
+		partnerAPIRoutes.POST("/codes", partnerController.IssueCode)
 	}
 	httpRouter.Run(":8081")
 
 }
+
+// newNotifier membuat notifier peringatan operasional. Peringatan selalu
+// ditulis ke log, dan juga dikirim ke webhook atau email jika env-nya diisi.
+func newNotifier() notification.Notifier {
+	sinks := []notification.Sink{notification.LogSink{}}
+	if url := os.Getenv("NOTIFY_WEBHOOK_URL"); url != "" {
+		sinks = append(sinks, notification.WebhookSink{URL: url})
+	}
+	if addr := os.Getenv("NOTIFY_SMTP_ADDR"); addr != "" {
+		var to []string
+		for _, recipient := range strings.Split(os.Getenv("NOTIFY_SMTP_TO"), ",") {
+			if recipient = strings.TrimSpace(recipient); recipient != "" {
+				to = append(to, recipient)
+			}
+		}
+		if len(to) == 0 {
+			log.Fatal("NOTIFY_SMTP_TO: at least one recipient is required when NOTIFY_SMTP_ADDR is set")
+		}
+		sinks = append(sinks, notification.SMTPSink{
+			Addr:     addr,
+			Username: os.Getenv("NOTIFY_SMTP_USERNAME"),
+			Password: os.Getenv("NOTIFY_SMTP_PASSWORD"),
+			From:     os.Getenv("NOTIFY_SMTP_FROM"),
+			To:       to,
+		})
+	}
+	return notification.NewDispatcher(durationFromEnv("NOTIFY_DEDUP_WINDOW", time.Hour), sinks...)
+}
+
+// appURL mengembalikan URL frontend untuk tautan di email dari APP_URL
+func appURL() string {
+	if url := os.Getenv("APP_URL"); url != "" {
+		return url
+	}
+	return "http://localhost:3000"
+}
+
+// rateLimitFromEnv membaca batas rate limit dari env key, atau fallback jika kosong
+func rateLimitFromEnv(key, fallback string) middleware.RateLimit {
+	value := os.Getenv(key)
+	if value == "" {
+		value = fallback
+	}
+	limit, err := middleware.ParseRateLimit(value)
+	if err != nil {
+		log.Fatalf("%s: %v", key, err)
+	}
+	return limit
+}
+
+// durationFromEnv membaca durasi dari env key, atau fallback jika kosong
+func durationFromEnv(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.Fatalf("%s: invalid duration %q", key, value)
+	}
+	return d
+}
+
+// normalizeCodeKey menyamakan penulisan kode redeem, misalnya "abcd-efg" dan "ABCDEFG"
+func normalizeCodeKey(value string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(value))
+}
+
+// normalizePhoneKey menyamakan format nomor HP agar 0812... dan +62812... berbagi bucket
+func normalizePhoneKey(value string) string {
+	number, err := phone.Normalize(value)
+	if err != nil {
+		return value
+	}
+	return number.E164
+}
+
+// normalizeMFATokenKey mengganti challenge token dengan user ID-nya, agar
+// lockout kode 2FA berlaku per user walaupun user sign in ulang untuk token baru
+func normalizeMFATokenKey(value string) string {
+	challenge, err := utils.ValidateMFAToken(value)
+	if err != nil {
+		return value
+	}
+	return fmt.Sprint("user:", challenge.UserID)
+}
+
+// normalizeEmailKey menyamakan penulisan email untuk kunci rate limit
+func normalizeEmailKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
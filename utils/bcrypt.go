@@ -1,10 +1,11 @@
 package utils
 
 import (
-	"fmt"
-	"os"
+	"errors"
 	"time"
 
+	"github.com/gamaput/go-redeem/jwtkeys"
+
 	"github.com/dgrijalva/jwt-go"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -19,29 +20,89 @@ func ComparePassword(dbPass, pass string) bool {
 	return bcrypt.CompareHashAndPassword([]byte(dbPass), []byte(pass)) == nil
 }
 
-//GenerateToken -> generates token
-func GenerateToken(userid uint) string {
+// AccessTokenTTL adalah masa berlaku access token; sesi diperpanjang dengan refresh token
+const AccessTokenTTL = 15 * time.Minute
+
+// keyRing dipakai untuk menandatangani dan memverifikasi access token, diatur saat startup
+var keyRing *jwtkeys.Ring
+
+// SetKeyRing mengatur key ring untuk GenerateToken dan ValidateToken
+func SetKeyRing(ring *jwtkeys.Ring) {
+	keyRing = ring
+}
+
+//GenerateToken -> generates access token for the user's session, signed with the active key
+func GenerateToken(userid, sessionID uint) (string, error) {
+	if keyRing == nil {
+		return "", jwtkeys.ErrNoSigningKey
+	}
 	claims := jwt.MapClaims{
-		"exp":    time.Now().Add(time.Hour * 3).Unix(),
+		"exp":       time.Now().Add(AccessTokenTTL).Unix(),
+		"iat":       time.Now().Unix(),
+		"userID":    userid,
+		"sessionID": sessionID,
+	}
+	return keyRing.Sign(claims)
+}
+
+// MFATokenTTL adalah masa berlaku challenge token setelah password benar pada akun dengan 2FA
+const MFATokenTTL = 5 * time.Minute
+
+// GenerateMFAToken -> generates a challenge token that can only be exchanged for
+// an access token with a valid two-factor code, not used as an access token.
+// The jti claim lets the exchange reject a token that has already been used.
+func GenerateMFAToken(userid uint) (string, error) {
+	if keyRing == nil {
+		return "", jwtkeys.ErrNoSigningKey
+	}
+	jti, err := RandomToken(16)
+	if err != nil {
+		return "", err
+	}
+	claims := jwt.MapClaims{
+		"exp":    time.Now().Add(MFATokenTTL).Unix(),
 		"iat":    time.Now().Unix(),
+		"jti":    jti,
 		"userID": userid,
+		"mfa":    true,
 	}
+	return keyRing.Sign(claims)
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	t, _ := token.SignedString([]byte(os.Getenv("JWT_SECRET")))
-	return t
+// MFAChallenge adalah isi challenge token 2FA yang valid
+type MFAChallenge struct {
+	ID        string // jti, dicatat saat token ditukar agar hanya bisa dipakai sekali
+	UserID    uint
+	ExpiresAt time.Time
+}
 
+// ValidateMFAToken --> validate a challenge token and return its claims
+func ValidateMFAToken(tokenString string) (MFAChallenge, error) {
+	invalid := errors.New("not a valid mfa token")
+	token, err := ValidateToken(tokenString)
+	if err != nil {
+		return MFAChallenge{}, err
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid || claims["mfa"] != true {
+		return MFAChallenge{}, invalid
+	}
+	userID, ok := claims["userID"].(float64)
+	if !ok || userID <= 0 {
+		return MFAChallenge{}, invalid
+	}
+	jti, ok := claims["jti"].(string)
+	if !ok || jti == "" {
+		return MFAChallenge{}, invalid
+	}
+	exp, _ := claims["exp"].(float64)
+	return MFAChallenge{ID: jti, UserID: uint(userID), ExpiresAt: time.Unix(int64(exp), 0)}, nil
 }
 
-//ValidateToken --> validate the given token
+//ValidateToken --> validate the given token against the key named by its kid header
 func ValidateToken(token string) (*jwt.Token, error) {
-
-	//2nd arg function return secret key after checking if the signing method is HMAC and returned key is used by 'Parse' to decode the token)
-	return jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			//nil secret key
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(os.Getenv("JWT_SECRET")), nil
-	})
+	if keyRing == nil {
+		return nil, jwtkeys.ErrNoSigningKey
+	}
+	return jwt.Parse(token, keyRing.Keyfunc)
 }
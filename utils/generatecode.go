@@ -1,22 +1,33 @@
 package utils
 
 import (
-	"crypto/rand"
-)
+	"errors"
 
-const (
-	uniqueCodeLength = 6
-	chars            = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	"github.com/gamaput/go-redeem/codeformat"
 )
 
-// GenerateUniqueCode generates a unique alphanumeric code with 6 digits
-func GenerateUniqueCode() string {
-	code := make([]byte, uniqueCodeLength)
-	rand.Read(code)
+// maxCodeAttempts membatasi percobaan ulang ketika kode yang dibuat sudah dipakai
+const maxCodeAttempts = 10
 
-	for i := 0; i < uniqueCodeLength; i++ {
-		code[i] = chars[code[i]%byte(len(chars))]
-	}
+// ErrCodeSpaceExhausted dikembalikan jika tidak ada kode unik yang ditemukan
+// setelah maxCodeAttempts percobaan
+var ErrCodeSpaceExhausted = errors.New("failed to generate a unique code")
 
-	return string(code)
+// GenerateUniqueCode generates a code in the given format that isTaken
+// reports as unused, retrying on collisions up to maxCodeAttempts times
+func GenerateUniqueCode(spec codeformat.Spec, isTaken func(code string) (bool, error)) (string, error) {
+	for attempt := 0; attempt < maxCodeAttempts; attempt++ {
+		code, err := spec.Generate()
+		if err != nil {
+			return "", err
+		}
+		taken, err := isTaken(code)
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return code, nil
+		}
+	}
+	return "", ErrCodeSpaceExhausted
 }
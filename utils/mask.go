@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// MaskTail menyamarkan value kecuali visible karakter terakhir, misalnya
+// MaskTail("3171234567890001", 4) menjadi "************0001"
+func MaskTail(value string, visible int) string {
+	runes := []rune(value)
+	if len(runes) <= visible {
+		return strings.Repeat("*", len(runes))
+	}
+	return strings.Repeat("*", len(runes)-visible) + string(runes[len(runes)-visible:])
+}
+
+// MaskName menyamarkan setiap kata pada nama kecuali huruf pertamanya,
+// misalnya "Budi Santoso" menjadi "B*** S******"
+func MaskName(name string) string {
+	words := strings.Fields(name)
+	for i, word := range words {
+		first, size := utf8.DecodeRuneInString(word)
+		words[i] = string(first) + strings.Repeat("*", utf8.RuneCountInString(word[size:]))
+	}
+	return strings.Join(words, " ")
+}
+
+// MaskPhone menyamarkan nomor telepon E.164 kecuali kode negara dan 4 digit terakhir,
+// misalnya "+6281234567890" menjadi "+62*******7890"
+func MaskPhone(e164 string) string {
+	if strings.HasPrefix(e164, "+62") {
+		return "+62" + MaskTail(e164[3:], 4)
+	}
+	return MaskTail(e164, 4)
+}
@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"math/rand"
+	"sync"
+
+	"github.com/gamaput/go-redeem/model"
+)
+
+// lockedSource membungkus rand.Source agar aman dipakai oleh banyak goroutine
+type lockedSource struct {
+	mu  sync.Mutex
+	src rand.Source64
+}
+
+func (s *lockedSource) Int63() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Int63()
+}
+
+func (s *lockedSource) Uint64() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Uint64()
+}
+
+func (s *lockedSource) Seed(seed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.src.Seed(seed)
+}
+
+// NewRand returns a goroutine-safe *rand.Rand seeded with seed
+func NewRand(seed int64) *rand.Rand {
+	return rand.New(&lockedSource{src: rand.NewSource(seed).(rand.Source64)})
+}
+
+// PickWeightedPrize memilih satu hadiah dari prizes dengan peluang sebanding
+// dengan Weight masing-masing. noPrizeWeight adalah bobot untuk hasil
+// "tidak dapat hadiah"; jika hasil itu yang terpilih, ok bernilai false.
+// Hadiah dengan stok habis atau bobot 0 tidak pernah terpilih.
+func PickWeightedPrize(rng *rand.Rand, prizes []model.Prize, noPrizeWeight uint) (prize model.Prize, ok bool) {
+	total := uint64(noPrizeWeight)
+	for _, p := range prizes {
+		if p.Quantity > 0 {
+			total += uint64(p.Weight)
+		}
+	}
+	if total == 0 {
+		return model.Prize{}, false
+	}
+
+	n := uint64(rng.Int63n(int64(total)))
+	for _, p := range prizes {
+		if p.Quantity <= 0 || p.Weight == 0 {
+			continue
+		}
+		if n < uint64(p.Weight) {
+			return p, true
+		}
+		n -= uint64(p.Weight)
+	}
+	return model.Prize{}, false
+}
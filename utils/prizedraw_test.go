@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gamaput/go-redeem/model"
+)
+
+func TestPickWeightedPrize(t *testing.T) {
+	const draws = 100000
+
+	prize := func(id uint, quantity int, weight uint) model.Prize {
+		p := model.Prize{Quantity: quantity, Weight: weight}
+		p.ID = id
+		return p
+	}
+
+	tests := []struct {
+		name          string
+		prizes        []model.Prize
+		noPrizeWeight uint
+		// want adalah peluang setiap hasil; key 0 berarti "tidak dapat hadiah"
+		want map[uint]float64
+	}{
+		{
+			name:   "weights are proportional",
+			prizes: []model.Prize{prize(1, 10, 1), prize(2, 10, 3)},
+			want:   map[uint]float64{1: 0.25, 2: 0.75},
+		},
+		{
+			name:          "no-prize outcome has its own weight",
+			prizes:        []model.Prize{prize(1, 10, 1)},
+			noPrizeWeight: 3,
+			want:          map[uint]float64{0: 0.75, 1: 0.25},
+		},
+		{
+			name:          "sold out and zero weight prizes are never drawn",
+			prizes:        []model.Prize{prize(1, 0, 5), prize(2, 10, 0), prize(3, 10, 1)},
+			noPrizeWeight: 1,
+			want:          map[uint]float64{0: 0.5, 3: 0.5},
+		},
+		{
+			name:   "nothing to draw",
+			prizes: []model.Prize{prize(1, 0, 1)},
+			want:   map[uint]float64{0: 1},
+		},
+		{
+			name:          "only no-prize",
+			noPrizeWeight: 1,
+			want:          map[uint]float64{0: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rng := NewRand(42)
+			counts := map[uint]int{}
+			for i := 0; i < draws; i++ {
+				got, ok := PickWeightedPrize(rng, tt.prizes, tt.noPrizeWeight)
+				if ok != (got.ID != 0) {
+					t.Fatalf("ok = %v for prize ID %d", ok, got.ID)
+				}
+				counts[got.ID]++
+			}
+
+			for id := range counts {
+				if _, ok := tt.want[id]; !ok {
+					t.Errorf("prize %d drawn %d times, want never", id, counts[id])
+				}
+			}
+			for id, want := range tt.want {
+				got := float64(counts[id]) / draws
+				if math.Abs(got-want) > 0.01 {
+					t.Errorf("prize %d drawn with probability %.3f, want %.3f", id, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestPickWeightedPrizeSeeded(t *testing.T) {
+	prizes := []model.Prize{{Quantity: 1, Weight: 1}, {Quantity: 1, Weight: 1}}
+	prizes[0].ID, prizes[1].ID = 1, 2
+
+	first, second := NewRand(7), NewRand(7)
+	for i := 0; i < 100; i++ {
+		a, _ := PickWeightedPrize(first, prizes, 1)
+		b, _ := PickWeightedPrize(second, prizes, 1)
+		if a.ID != b.ID {
+			t.Fatalf("draw %d: same seed gave prize %d and %d", i, a.ID, b.ID)
+		}
+	}
+}
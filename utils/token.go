@@ -0,0 +1,24 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// RandomToken mengembalikan n byte acak dari crypto/rand dalam bentuk hex,
+// untuk secret dan token yang tidak boleh bisa ditebak
+func RandomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// HashToken mengembalikan SHA-256 token dalam bentuk hex untuk disimpan dan
+// dicari di database; token acak yang panjang tidak memerlukan bcrypt
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
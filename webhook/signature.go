@@ -0,0 +1,74 @@
+// Package webhook mengirim event dari outbox ke pelanggan webhook dengan
+// tanda tangan HMAC-SHA256 dan percobaan ulang bertahap.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Header yang dikirim pada setiap pengiriman webhook
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+)
+
+var (
+	// ErrInvalidSignature dikembalikan jika header tanda tangan tidak cocok dengan body
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	// ErrSignatureExpired dikembalikan jika timestamp tanda tangan di luar toleransi
+	ErrSignatureExpired = errors.New("webhook signature timestamp outside tolerance")
+)
+
+// Sign mengembalikan nilai header X-Webhook-Signature dengan format
+// "t=<unix timestamp>,v1=<hex HMAC-SHA256>". HMAC dihitung dari
+// "<timestamp>.<body>" agar timestamp ikut ditandatangani.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	return fmt.Sprintf("t=%s,v1=%s", ts, signature(secret, ts, body))
+}
+
+// Verify memeriksa header X-Webhook-Signature, dipakai oleh penerima webhook.
+// Tanda tangan yang lebih tua atau lebih baru dari tolerance ditolak.
+func Verify(secret, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	var ts, sig string
+	for _, part := range strings.Split(header, ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "t":
+			ts = kv[1]
+		case "v1":
+			sig = kv[1]
+		}
+	}
+
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || sig == "" {
+		return ErrInvalidSignature
+	}
+	if age := now.Sub(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+		return ErrSignatureExpired
+	}
+	if !hmac.Equal([]byte(sig), []byte(signature(secret, ts, body))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func signature(secret, ts string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
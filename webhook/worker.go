@@ -0,0 +1,190 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gamaput/go-redeem/model"
+	"github.com/gamaput/go-redeem/repository"
+)
+
+const (
+	// MaxAttempts adalah jumlah percobaan sebelum pengiriman berstatus dead
+	MaxAttempts = 8
+	// baseBackoff adalah jeda sebelum percobaan ulang pertama; jeda berikutnya dua kali lipat
+	baseBackoff = 30 * time.Second
+	// maxBackoff membatasi jeda antar percobaan
+	maxBackoff = 6 * time.Hour
+	// maxErrorLength membatasi panjang pesan error yang disimpan
+	maxErrorLength = 1000
+)
+
+// Backoff mengembalikan jeda sebelum percobaan berikutnya setelah attempts kali gagal
+func Backoff(attempts int) time.Duration {
+	d := baseBackoff
+	for i := 1; i < attempts && d < maxBackoff; i++ {
+		d *= 2
+	}
+	if d > maxBackoff {
+		d = maxBackoff
+	}
+	return d
+}
+
+// envelope adalah body JSON yang dikirim ke pelanggan
+type envelope struct {
+	ID        uint            `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Worker memindahkan event dari outbox ke antrean pengiriman dan mengirimkannya
+type Worker struct {
+	Repo      repository.WebhookRepository
+	Client    *http.Client
+	Interval  time.Duration // jeda antar putaran
+	BatchSize int           // jumlah event dan pengiriman per putaran
+	Lease     time.Duration // lama satu pengiriman dikunci sebelum dapat diambil lagi, harus lebih lama dari Client.Timeout
+}
+
+// NewWorker -> returns new webhook worker dengan pengaturan bawaan
+func NewWorker(repo repository.WebhookRepository) *Worker {
+	return &Worker{
+		Repo:      repo,
+		Client:    &http.Client{Timeout: 10 * time.Second},
+		Interval:  5 * time.Second,
+		BatchSize: 100,
+		Lease:     time.Minute,
+	}
+}
+
+// Run menjalankan worker sampai stop ditutup
+func (w *Worker) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+	for {
+		w.RunOnce()
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce mempublikasikan event outbox lalu mengirim pengiriman yang sudah waktunya
+func (w *Worker) RunOnce() {
+	for {
+		published, err := w.Repo.PublishOutboxEvents(w.BatchSize)
+		if err != nil {
+			log.Println("webhook: failed to publish outbox events:", err)
+			break
+		}
+		if published < w.BatchSize {
+			break
+		}
+	}
+
+	// Pengiriman diambil satu per satu tepat sebelum dikirim, sehingga lease
+	// cukup mencakup satu request dan tidak habis selagi pengiriman lain
+	// dalam putaran yang sama masih berjalan
+	for i := 0; i < w.BatchSize; i++ {
+		due, err := w.Repo.ClaimDueDeliveries(time.Now(), w.Lease, 1)
+		if err != nil {
+			log.Println("webhook: failed to claim deliveries:", err)
+			return
+		}
+		if len(due) == 0 {
+			return
+		}
+		w.deliver(due[0])
+	}
+}
+
+// deliver mengirim satu pengiriman dan mencatat hasilnya
+func (w *Worker) deliver(due repository.DueDelivery) {
+	delivery := due.Delivery
+	delivery.Attempts++
+	attempt := model.WebhookDeliveryAttempt{}
+
+	start := time.Now()
+	statusCode, err := w.send(due)
+	attempt.DurationMs = time.Since(start).Milliseconds()
+	attempt.StatusCode = statusCode
+	delivery.LastStatusCode = statusCode
+
+	now := time.Now()
+	switch {
+	case err == nil:
+		delivery.Status = model.DeliveryStatusSucceeded
+		delivery.DeliveredAt = &now
+		delivery.LastError = ""
+	case due.Subscription.ID == 0 || !due.Subscription.Active:
+		// Langganan dihapus atau dinonaktifkan, pengiriman tidak dicoba lagi
+		delivery.Status = model.DeliveryStatusDead
+	case delivery.Attempts >= MaxAttempts:
+		delivery.Status = model.DeliveryStatusDead
+	default:
+		delivery.NextAttemptAt = now.Add(Backoff(delivery.Attempts))
+	}
+	if err != nil {
+		attempt.Error = truncate(err.Error(), maxErrorLength)
+		delivery.LastError = attempt.Error
+	}
+
+	if err := w.Repo.RecordDeliveryAttempt(delivery, attempt); err != nil {
+		log.Printf("webhook: failed to record delivery %d: %v", delivery.ID, err)
+	}
+}
+
+func (w *Worker) send(due repository.DueDelivery) (int, error) {
+	if due.Subscription.ID == 0 || !due.Subscription.Active {
+		return 0, fmt.Errorf("subscription %d is no longer active", due.Delivery.SubscriptionID)
+	}
+
+	body, err := json.Marshal(envelope{
+		ID:        due.Event.ID,
+		Type:      due.Event.Type,
+		CreatedAt: due.Event.CreatedAt,
+		Data:      json.RawMessage(due.Event.Payload),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, due.Subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "go-redeem-webhook")
+	req.Header.Set(EventHeader, due.Event.Type)
+	req.Header.Set(DeliveryHeader, strconv.FormatUint(uint64(due.Delivery.ID), 10))
+	req.Header.Set(SignatureHeader, Sign(due.Subscription.Secret, time.Now(), body))
+
+	resp, err := w.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	snippet, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, snippet)
+	}
+	return resp.StatusCode, nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}